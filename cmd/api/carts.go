@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+)
+
+func (app *application) showCartHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	cart, err := app.models.Carts.Get(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"cart": cart}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ComicsID  int64  `json:"comics_id"`
+		VariantID *int64 `json:"variant_id"`
+		Quantity  int32  `json:"quantity"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Quantity == 0 {
+		input.Quantity = 1
+	}
+
+	v := validator.New()
+	v.Check(input.ComicsID > 0, "comics_id", "must be provided")
+	v.Check(input.Quantity > 0, "quantity", "must be a positive integer")
+	v.Check(input.Quantity <= data.MaxCartQuantity, "quantity", "must not be more than 100")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	comics, err := app.models.Comics.Get(input.ComicsID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("comics_id", "must reference an existing issue")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if input.VariantID != nil {
+		found := false
+		for _, variant := range comics.Variants {
+			found = found || variant.ID == *input.VariantID
+		}
+		if !found {
+			v.AddError("variant_id", "must reference a variant of this issue")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	item := &data.CartItem{
+		UserID:    app.contextGetUser(r).ID,
+		ComicsID:  comics.ID,
+		VariantID: input.VariantID,
+		Quantity:  input.Quantity,
+	}
+
+	err = app.models.Carts.AddItem(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCartQuantity):
+			v.AddError("quantity", "must not take the item over 100 copies")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.showCartHandler(w, r)
+}
+
+func (app *application) updateCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	item, err := app.models.Carts.GetItem(user.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Quantity int32 `json:"quantity"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Quantity > 0, "quantity", "must be a positive integer")
+	v.Check(input.Quantity <= data.MaxCartQuantity, "quantity", "must not be more than 100")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	item.Quantity = input.Quantity
+
+	err = app.models.Carts.UpdateItem(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.showCartHandler(w, r)
+}
+
+func (app *application) deleteCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Carts.DeleteItem(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.showCartHandler(w, r)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (app *application) listCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PublisherID int64
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.PublisherID = int64(app.readInt(qs, "publisher_id", -1, v))
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+
+	input.Filters.SortSafelist = []string{
+		"id", "title", "grade_value", "purchase_price", "purchase_date",
+		"-id", "-title", "-grade_value", "-purchase_price", "-purchase_date",
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	items, metadata, err := app.models.Collection.GetAll(app.contextGetUser(r).ID, input.PublisherID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"collection": items, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The createCollectionItemHandler() adds a copy of an issue to the collection of the
+// current user. Grades are accepted in any case and spacing, and stored in their usual
+// spelling.
+func (app *application) createCollectionItemHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ComicsID      int64      `json:"comics_id"`
+		Grade         string     `json:"grade"`
+		PurchasePrice data.Price `json:"purchase_price"`
+		PurchaseDate  *data.Date `json:"purchase_date"`
+		Notes         string     `json:"notes"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	item := &data.CollectionItem{
+		UserID:        user.ID,
+		ComicsID:      input.ComicsID,
+		Grade:         data.NormalizeGrade(input.Grade),
+		PurchasePrice: input.PurchasePrice,
+		PurchaseDate:  input.PurchaseDate,
+		Notes:         input.Notes,
+	}
+
+	v := validator.New()
+
+	_, err = app.models.Comics.Get(input.ComicsID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("comics_id", "must reference an existing issue")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if data.ValidateCollectionItem(v, item); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Collection.Insert(item)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Read the item back for the title, issue number and publisher of its issue.
+	item, err = app.models.Collection.Get(user.ID, item.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/users/me/collection/items/%d", item.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"item": item}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCollectionItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	item, err := app.models.Collection.Get(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCollectionItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	item, err := app.models.Collection.Get(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Grade         *string     `json:"grade"`
+		PurchasePrice *data.Price `json:"purchase_price"`
+		PurchaseDate  *data.Date  `json:"purchase_date"`
+		Notes         *string     `json:"notes"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Grade != nil {
+		item.Grade = data.NormalizeGrade(*input.Grade)
+	}
+	if input.PurchasePrice != nil {
+		item.PurchasePrice = *input.PurchasePrice
+	}
+	if input.PurchaseDate != nil {
+		item.PurchaseDate = input.PurchaseDate
+	}
+	if input.Notes != nil {
+		item.Notes = *input.Notes
+	}
+
+	v := validator.New()
+	if data.ValidateCollectionItem(v, item); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Collection.Update(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCollectionItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Collection.Delete(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "item successfully removed from collection"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) collectionStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := app.models.Collection.GetStats(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The exportCollectionHandler() sends the whole collection of the current user as a CSV
+// file, with a header line naming the columns. Prices are decimal amounts and dates are
+// formatted as 2006-01-02, so the file opens cleanly in a spreadsheet.
+func (app *application) exportCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	items, err := app.models.Collection.GetAllForExport(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+
+	cw.Write([]string{
+		"id", "comics_id", "title", "issue_number", "publisher", "grade", "grade_value",
+		"purchase_price", "purchase_date", "notes", "added_at",
+	})
+	for _, item := range items {
+		var purchaseDate string
+		if item.PurchaseDate != nil {
+			purchaseDate = item.PurchaseDate.String()
+		}
+		cw.Write([]string{
+			strconv.FormatInt(item.ID, 10),
+			strconv.FormatInt(item.ComicsID, 10),
+			item.Title,
+			string(item.IssueNumber),
+			item.Publisher,
+			string(item.Grade),
+			strconv.FormatFloat(item.GradeValue, 'f', 1, 64),
+			item.PurchasePrice.String(),
+			purchaseDate,
+			item.Notes,
+			item.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="collection.csv"`)
+	w.Write(buf.Bytes())
+}
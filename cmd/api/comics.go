@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/miras210/finalGolang/internal/data"
@@ -14,9 +15,22 @@ import (
 func (app *application) createComicsHandler(w http.ResponseWriter, r *http.Request) {
 
 	var input struct {
-		Title string     `json:"title"`
-		Year  int32      `json:"year"`
-		Pages data.Pages `json:"pages"`
+		Title       string           `json:"title"`
+		Summary     string           `json:"summary"`
+		Year        int32            `json:"year"`
+		ReleaseDate *data.Date       `json:"release_date"`
+		OnSaleDate  *data.Date       `json:"on_sale_date"`
+		FOCDate     *data.Date       `json:"foc_date"`
+		Pages       data.Pages       `json:"pages"`
+		SeriesID    *int64           `json:"series_id"`
+		IssueNumber data.IssueNumber `json:"issue_number"`
+		PublisherID *int64           `json:"publisher_id"`
+		ImprintID   *int64           `json:"imprint_id"`
+		ItemCode    string           `json:"item_code"`
+		UPC         string           `json:"upc"`
+		Price       data.Price       `json:"price"`
+		Credits     []data.Credit    `json:"credits"`
+		Variants    []variantInput   `json:"variants"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -26,9 +40,30 @@ func (app *application) createComicsHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	comics := &data.Comics{
-		Title: input.Title,
-		Year:  input.Year,
-		Pages: input.Pages,
+		Title:       input.Title,
+		Summary:     input.Summary,
+		Year:        input.Year,
+		ReleaseDate: input.ReleaseDate,
+		OnSaleDate:  input.OnSaleDate,
+		FOCDate:     input.FOCDate,
+		Pages:       input.Pages,
+		SeriesID:    input.SeriesID,
+		IssueNumber: input.IssueNumber,
+		PublisherID: input.PublisherID,
+		ImprintID:   input.ImprintID,
+		ItemCode:    input.ItemCode,
+		UPC:         input.UPC,
+		Price:       input.Price,
+		Credits:     input.Credits,
+	}
+
+	// The year can be left out when the full release date is known.
+	if comics.Year == 0 && comics.ReleaseDate != nil {
+		comics.Year = int32(comics.ReleaseDate.Year())
+	}
+
+	for _, variant := range input.Variants {
+		comics.Variants = append(comics.Variants, variant.toVariant())
 	}
 
 	v := validator.New()
@@ -38,11 +73,44 @@ func (app *application) createComicsHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Comics.Insert(comics)
+	err = app.validateComicsImprint(v, comics)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Comics.Insert(comics)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnknownSeries):
+			v.AddError("series_id", "must reference an existing series")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownPublisher):
+			v.AddError("publisher_id", "must reference an existing publisher")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownCreator):
+			v.AddError("credits", "must only reference existing creators")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateSKU):
+			v.AddError("variants", "a variant with this sku already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateItemCode):
+			v.AddError("item_code", "an issue with this item code already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateUPC):
+			v.AddError("upc", "an issue with this upc already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.notifyHolds(comics.ID)
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/comics/%d", comics.ID))
@@ -74,6 +142,12 @@ func (app *application) showComicsHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	comics.Credits, err = app.models.Creators.GetCredits(comics.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"comics": comics}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -106,9 +180,21 @@ func (app *application) updateComicsHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	var input struct {
-		Title *string     `json:"title"`
-		Year  *int32      `json:"year"`
-		Pages *data.Pages `json:"pages"`
+		Title       *string           `json:"title"`
+		Summary     *string           `json:"summary"`
+		Year        *int32            `json:"year"`
+		ReleaseDate json.RawMessage   `json:"release_date"`
+		OnSaleDate  json.RawMessage   `json:"on_sale_date"`
+		FOCDate     json.RawMessage   `json:"foc_date"`
+		Pages       *data.Pages       `json:"pages"`
+		SeriesID    json.RawMessage   `json:"series_id"`
+		IssueNumber *data.IssueNumber `json:"issue_number"`
+		PublisherID json.RawMessage   `json:"publisher_id"`
+		ImprintID   json.RawMessage   `json:"imprint_id"`
+		ItemCode    *string           `json:"item_code"`
+		UPC         *string           `json:"upc"`
+		Price       *data.Price       `json:"price"`
+		Credits     *[]data.Credit    `json:"credits"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -120,12 +206,66 @@ func (app *application) updateComicsHandler(w http.ResponseWriter, r *http.Reque
 	if input.Title != nil {
 		comics.Title = *input.Title
 	}
+	if input.Summary != nil {
+		comics.Summary = *input.Summary
+	}
 	if input.Year != nil {
 		comics.Year = *input.Year
 	}
+	// The dates are kept as raw JSON too, so that a wrongly announced date can be removed
+	// by setting it to null.
+	err = app.readNullable(input.ReleaseDate, "release_date", &comics.ReleaseDate)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.ReleaseDate != nil && comics.ReleaseDate != nil && input.Year == nil {
+		comics.Year = int32(comics.ReleaseDate.Year())
+	}
+	err = app.readNullable(input.OnSaleDate, "on_sale_date", &comics.OnSaleDate)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	err = app.readNullable(input.FOCDate, "foc_date", &comics.FOCDate)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 	if input.Pages != nil {
 		comics.Pages = *input.Pages
 	}
+	// Likewise a null series, publisher or imprint takes the issue out of it.
+	err = app.readNullable(input.SeriesID, "series_id", &comics.SeriesID)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	err = app.readNullable(input.PublisherID, "publisher_id", &comics.PublisherID)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	err = app.readNullable(input.ImprintID, "imprint_id", &comics.ImprintID)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.IssueNumber != nil {
+		comics.IssueNumber = *input.IssueNumber
+	}
+	if input.ItemCode != nil {
+		comics.ItemCode = *input.ItemCode
+	}
+	if input.UPC != nil {
+		comics.UPC = *input.UPC
+	}
+	if input.Price != nil {
+		comics.Price = *input.Price
+	}
+	if input.Credits != nil {
+		comics.Credits = *input.Credits
+	}
 
 	v := validator.New()
 	if data.ValidateComics(v, comics); !v.Valid() {
@@ -133,11 +273,36 @@ func (app *application) updateComicsHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	err = app.validateComicsImprint(v, comics)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	err = app.models.Comics.Update(comics)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrUnknownSeries):
+			v.AddError("series_id", "must reference an existing series")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownPublisher):
+			v.AddError("publisher_id", "must reference an existing publisher")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownCreator):
+			v.AddError("credits", "must only reference existing creators")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateItemCode):
+			v.AddError("item_code", "an issue with this item code already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateUPC):
+			v.AddError("upc", "an issue with this upc already exists")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -177,8 +342,7 @@ func (app *application) deleteComicsHandler(w http.ResponseWriter, r *http.Reque
 
 func (app *application) listComicsHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title string
-		Year  int
+		data.ComicsFilter
 		data.Filters
 	}
 
@@ -187,28 +351,65 @@ func (app *application) listComicsHandler(w http.ResponseWriter, r *http.Request
 	qs := r.URL.Query()
 
 	input.Title = app.readString(qs, "title", "")
-	input.Year = app.readInt(qs, "year", -1, v)
+	input.Year = app.readInt(qs, "year", 0, v)
+	input.CreatorID = app.readInt(qs, "creator", 0, v)
+	input.SeriesID = app.readInt(qs, "series_id", 0, v)
+	input.PublisherID = app.readInt(qs, "publisher", 0, v)
+	input.InStock = app.readBool(qs, "in_stock", v)
+	input.Read = app.readBool(qs, "read", v)
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
 
-	input.Filters.SortSafelist = []string{"id", "title", "year", "-id", "-title", "-year"}
+	input.Filters.SortSafelist = []string{"id", "title", "year", "issue_number", "rating", "-id", "-title", "-year", "-issue_number", "-rating"}
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	comics, metadata, err := app.models.Comics.GetAll(input.Title, input.Year, input.Filters)
+	input.ReaderID = app.contextGetUser(r).ID
+
+	comics, metadata, err := app.models.Comics.GetAll(input.ComicsFilter, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	publishers, err := app.models.Comics.GetPublisherFacets(input.ComicsFilter)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"comics": comics, "metadata": metadata}, nil)
+	facets := map[string][]data.Facet{"publishers": publishers}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"comics": comics, "metadata": metadata, "facets": facets}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 
 }
+
+// The validateComicsImprint() helper checks that the imprint of a comics issue (if any)
+// exists and belongs to the issue's publisher, recording any problem in the validator.
+func (app *application) validateComicsImprint(v *validator.Validator, comics *data.Comics) error {
+	if comics.ImprintID == nil {
+		return nil
+	}
+
+	imprint, err := app.models.Publishers.GetImprint(*comics.ImprintID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("imprint_id", "must reference an existing imprint")
+			return nil
+		default:
+			return err
+		}
+	}
+
+	v.Check(comics.PublisherID != nil && imprint.PublisherID == *comics.PublisherID, "imprint_id", "must belong to the given publisher")
+	return nil
+}
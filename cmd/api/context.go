@@ -14,6 +14,14 @@ type contextKey string
 // in the request context.
 const userContextKey = contextKey("user")
 
+// The tokenContextKey holds the plaintext authentication token of the request, so that
+// handlers can tell which session is the current one.
+const tokenContextKey = contextKey("token")
+
+// The apiKeyContextKey holds the API key a request was authenticated with, whose
+// permissions limit what the request may do.
+const apiKeyContextKey = contextKey("apiKey")
+
 // The contextSetUser() method returns a new copy of the request with the provided
 // User struct added to the context. Note that we use our userContextKey constant as the
 // key.
@@ -33,3 +41,32 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+// The contextSetToken() method returns a new copy of the request with the plaintext
+// authentication token it was made with added to the context.
+func (app *application) contextSetToken(r *http.Request, token string) *http.Request {
+	ctx := context.WithValue(r.Context(), tokenContextKey, token)
+	return r.WithContext(ctx)
+}
+
+// The contextGetToken() retrieves the plaintext authentication token of the request. It
+// returns an empty string for anonymous requests and requests using HTTP Basic
+// authentication, which have no token.
+func (app *application) contextGetToken(r *http.Request) string {
+	token, _ := r.Context().Value(tokenContextKey).(string)
+	return token
+}
+
+// The contextSetAPIKey() method returns a new copy of the request with the API key it
+// was authenticated with added to the context.
+func (app *application) contextSetAPIKey(r *http.Request, key *data.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
+// The contextGetAPIKey() retrieves the API key of the request, or nil if the request
+// wasn't authenticated with one.
+func (app *application) contextGetAPIKey(r *http.Request) *data.APIKey {
+	key, _ := r.Context().Value(apiKeyContextKey).(*data.APIKey)
+	return key
+}
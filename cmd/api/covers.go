@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/imaging"
+	"github.com/miras210/finalGolang/internal/validator"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"time"
+)
+
+// maxCoverBytes is the largest cover image we accept for upload.
+const maxCoverBytes = 10 << 20
+
+// coverSizes lists the thumbnails generated for every cover, by name and maximum width
+// in pixels. The uploaded image itself is kept too, under the "original" name.
+var coverSizes = []struct {
+	name  string
+	width int
+}{
+	{"small", 150},
+	{"medium", 300},
+	{"large", 600},
+}
+
+// coverTypes maps the content types accepted for covers to their file extension.
+var coverTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+}
+
+// maxCoverDimension is the largest width and height in pixels we accept for a cover.
+// A small compressed file can declare huge dimensions, and decoding it would take
+// several gigabytes of memory.
+const maxCoverDimension = 10000
+
+var (
+	errUnsupportedCoverType = errors.New("unsupported cover type")
+	errCoverTooLarge        = errors.New("cover too large")
+)
+
+func (app *application) updateComicsCoverHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	comics, err := app.models.Comics.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	content, err := app.readFile(w, r, "cover", maxCoverBytes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	urls, err := app.storeCover(comics.ID, content)
+	if err != nil {
+		switch {
+		case errors.Is(err, errUnsupportedCoverType):
+			app.unsupportedMediaTypeResponse(w, r, http.DetectContentType(content))
+		case errors.Is(err, errCoverTooLarge):
+			v := validator.New()
+			v.AddError("cover", fmt.Sprintf("must not be larger than %dx%d pixels", maxCoverDimension, maxCoverDimension))
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	comics.CoverURLs = urls
+
+	err = app.models.Comics.Update(comics)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"comics": comics}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The storeCover() helper sniffs the content type of an uploaded cover image, generates
+// its thumbnails and writes all of them to the blob store. It returns the public URL of
+// every size. A cache-busting query string is added to the URLs because the blob keys
+// are the same every time a new cover is uploaded for an issue.
+// Images wider or taller than maxCoverDimension are refused with errCoverTooLarge before
+// they are decoded.
+func (app *application) storeCover(comicsID int64, content []byte) (data.CoverURLs, error) {
+	ext, ok := coverTypes[http.DetectContentType(content)]
+	if !ok {
+		return nil, errUnsupportedCoverType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, errUnsupportedCoverType
+	}
+	if config.Width > maxCoverDimension || config.Height > maxCoverDimension {
+		return nil, errCoverTooLarge
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, errUnsupportedCoverType
+	}
+
+	stamp := time.Now().Unix()
+	urls := data.CoverURLs{}
+
+	key := fmt.Sprintf("covers/%d/original.%s", comicsID, ext)
+	err = app.storage.Put(key, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	urls["original"] = fmt.Sprintf("%s?v=%d", app.storage.URL(key), stamp)
+
+	for _, size := range coverSizes {
+		var buf bytes.Buffer
+		err = imaging.Encode(&buf, imaging.Resize(img, size.width), format)
+		if err != nil {
+			return nil, err
+		}
+
+		key := fmt.Sprintf("covers/%d/%s.%s", comicsID, size.name, ext)
+		err = app.storage.Put(key, &buf)
+		if err != nil {
+			return nil, err
+		}
+		urls[size.name] = fmt.Sprintf("%s?v=%d", app.storage.URL(key), stamp)
+	}
+
+	return urls, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"github.com/miras210/finalGolang/internal/cbz"
 	"net/http"
 )
 
@@ -60,11 +61,33 @@ func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Reques
 	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 }
 
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, contentType string) {
+	message := fmt.Sprintf("the %s content type is not supported for this resource", contentType)
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
+
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
 	message := "unable to update the record due to an edit conflict, please try again"
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+func (app *application) paymentInProgressResponse(w http.ResponseWriter, r *http.Request) {
+	message := "a payment for this order is already in progress"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) insufficientStockResponse(w http.ResponseWriter, r *http.Request) {
+	message := "not enough stock is available to complete the request"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// The pageTooLargeResponse() method is sent for archive pages over cbz.MaxPageBytes. The
+// archive itself is at fault, so it isn't logged as a server error.
+func (app *application) pageTooLargeResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf("the page is larger than the %d MiB limit and can't be served", cbz.MaxPageBytes>>20)
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, message)
+}
+
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
@@ -81,6 +104,19 @@ func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
 }
 
+func (app *application) invalidRefreshTokenResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid, expired or already used refresh token"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+// basicChallenge is the WWW-Authenticate header value sent to HTTP Basic clients.
+const basicChallenge = `Basic realm="Comics Store", charset="UTF-8"`
+
+func (app *application) invalidBasicCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", basicChallenge)
+	app.invalidCredentialsResponse(w, r)
+}
+
 func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
 	message := "you must be authenticated to access this resource"
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
@@ -94,3 +130,8 @@ func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Requ
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (app *application) apiKeyNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this resource can't be accessed with an API key"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
@@ -2,11 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
+	"github.com/miras210/finalGolang/internal/data"
 	"github.com/miras210/finalGolang/internal/validator"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -26,6 +29,18 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// The readNamedIDParam() helper works like readIDParam(), but reads the URL parameter with
+// the given name. It is used by nested routes which carry a second id, for example
+// /v1/publishers/:id/imprints/:imprint_id.
+func (app *application) readNamedIDParam(r *http.Request, name string) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid %s parameter", name)
+	}
+	return id, nil
+}
+
 // Define a writeJSON() helper for sending responses. This takes the destination
 // http.ResponseWriter, the HTTP status code to send, the data to encode to JSON, and a
 // header map containing any additional HTTP headers we want to include in the response.
@@ -47,6 +62,22 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 	return nil
 }
 
+// The writeXML() helper sends an XML document, such as an OPDS feed, with the given
+// content type.
+func (app *application) writeXML(w http.ResponseWriter, status int, data interface{}, contentType string) error {
+	x, err := xml.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	w.Write(x)
+	w.Write([]byte("\n"))
+	return nil
+}
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -99,6 +130,73 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 	return nil
 }
 
+// The readNullable() helper decodes a field of a PATCH body that was read as a
+// json.RawMessage, so that an explicit null, which clears the field, can be told apart
+// from the field being left out. dst must point to a pointer, which is set to nil for
+// null and left untouched when the field was left out.
+func (app *application) readNullable(raw json.RawMessage, field string, dst interface{}) error {
+	if raw == nil {
+		return nil
+	}
+	err := json.Unmarshal(raw, dst)
+	if err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+		if errors.As(err, &unmarshalTypeError) {
+			return fmt.Errorf("body contains incorrect JSON type for field %q", field)
+		}
+		return err
+	}
+	return nil
+}
+
+// The readFile() helper reads the contents of a single file field from a
+// multipart/form-data request body. Like readJSON() it limits the size of the request
+// body with http.MaxBytesReader(), and translates the errors a client can cause into
+// plain-English messages.
+func (app *application) readFile(w http.ResponseWriter, r *http.Request, field string, maxBytes int64) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("body must be multipart/form-data")
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			switch {
+			case errors.Is(err, io.EOF):
+				return nil, fmt.Errorf("body must contain a %q file", field)
+			case err.Error() == "http: request body too large":
+				return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytes)
+			default:
+				return nil, err
+			}
+		}
+
+		if part.FormName() != field {
+			part.Close()
+			continue
+		}
+
+		content, err := io.ReadAll(part)
+		part.Close()
+		if err != nil {
+			switch {
+			case err.Error() == "http: request body too large":
+				return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytes)
+			default:
+				return nil, err
+			}
+		}
+
+		if len(content) == 0 {
+			return nil, fmt.Errorf("%q file must not be empty", field)
+		}
+		return content, nil
+	}
+}
+
 // The readString() helper returns a string value from the query string, or the provided
 // default value if no matching key could be found.
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
@@ -125,6 +223,44 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 	return strings.Split(csv, ",")
 }
 
+// The readBool() helper reads an optional boolean value from the query string. It returns
+// nil if no matching key could be found, and records an error message in the provided
+// Validator instance if the value couldn't be converted to a boolean.
+func (app *application) readBool(qs url.Values, key string, v *validator.Validator) *bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return nil
+	}
+
+	return &b
+}
+
+// The readDate() helper reads an optional "2006-01-02" date from the query string. It
+// returns nil if no matching key could be found, and records an error message in the
+// provided Validator instance if the value isn't a valid date.
+func (app *application) readDate(qs url.Values, key string, v *validator.Validator) *data.Date {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	d, err := data.ParseDate(s)
+	if err != nil {
+		v.AddError(key, "must be a date in the form 2006-01-02")
+		return nil
+	}
+
+	return &d
+}
+
 // The readInt() helper reads a string value from the query string and converts it to an
 // integer before returning. If no matching key could be found it returns the provided
 // default value. If the value couldn't be converted to an integer, then we record an
@@ -146,11 +282,22 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// The clientIP() helper returns the IP address a request came from, without the port.
+func (app *application) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
 // The background() helper accepts an arbitrary function as a parameter.
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
 
 	go func() {
+		defer app.wg.Done()
+
 		defer func() {
 			if err := recover(); err != nil {
 				app.logger.PrintError(fmt.Errorf("%s", err), nil)
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"github.com/miras210/finalGolang/internal/cbz"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/shipping"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxArchiveBytes is the largest comic archive we accept for upload.
+const maxArchiveBytes = 200 << 20
+
+// maxShippingListBytes is the largest shipping list we accept for upload.
+const maxShippingListBytes = 5 << 20
+
+// importReport tells the client what happened to each ComicInfo.xml element: which
+// comics field it was mapped to, and which elements were ignored.
+type importReport struct {
+	Created bool              `json:"created"`
+	Mapped  map[string]string `json:"mapped"`
+	Ignored []string          `json:"ignored"`
+}
+
+// The importCBZHandler() creates or updates an issue from an uploaded CBZ archive, using
+// the metadata in its ComicInfo.xml file. An existing issue is updated when the archive
+// names a series and issue number that are already in the catalogue.
+func (app *application) importCBZHandler(w http.ResponseWriter, r *http.Request) {
+	content, err := app.readFile(w, r, "file", maxArchiveBytes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	archive, err := cbz.Open(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	fields, err := archive.ComicInfo()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if fields == nil {
+		app.badRequestResponse(w, r, errors.New("archive must contain a ComicInfo.xml file"))
+		return
+	}
+
+	info := make(map[string]string)
+	report := importReport{Mapped: make(map[string]string), Ignored: []string{}}
+	for _, field := range fields {
+		switch field.Name {
+		case "Title", "Series", "Volume", "Number", "Year", "Month", "Day", "PageCount", "Writer", "Penciller", "Summary":
+			if field.Value != "" {
+				info[field.Name] = field.Value
+				continue
+			}
+		}
+		report.Ignored = append(report.Ignored, field.Name)
+	}
+
+	v := validator.New()
+
+	var series *data.Series
+	if info["Series"] != "" {
+		series, err = app.findOrCreateSeries(info["Series"], info["Volume"], info["Year"])
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		report.Mapped["Series"] = "series_id"
+		if info["Volume"] != "" {
+			report.Mapped["Volume"] = "series.volume"
+		}
+	} else if info["Volume"] != "" {
+		report.Ignored = append(report.Ignored, "Volume")
+	}
+
+	comics := &data.Comics{}
+	if series != nil && info["Number"] != "" {
+		comics, err = app.models.Comics.GetBySeriesIssue(series.ID, data.IssueNumber(info["Number"]))
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				comics = &data.Comics{}
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+	report.Created = comics.ID == 0
+
+	if series != nil {
+		comics.SeriesID = &series.ID
+	}
+	if info["Number"] != "" {
+		comics.IssueNumber = data.IssueNumber(info["Number"])
+		report.Mapped["Number"] = "issue_number"
+	}
+
+	switch {
+	case info["Title"] != "":
+		comics.Title = info["Title"]
+		report.Mapped["Title"] = "title"
+	case comics.Title == "" && series != nil:
+		comics.Title = strings.TrimSpace(series.Name + " #" + info["Number"])
+	}
+
+	if info["Year"] != "" {
+		year, err := strconv.Atoi(info["Year"])
+		if err != nil {
+			v.AddError("year", "must be an integer value")
+		}
+		comics.Year = int32(year)
+		report.Mapped["Year"] = "year"
+
+		// With a month, and optionally a day, the year becomes a full release date.
+		// Issues without a day are dated to the first of the month.
+		if month, err := strconv.Atoi(info["Month"]); err == nil && month >= 1 && month <= 12 {
+			day, err := strconv.Atoi(info["Day"])
+			if err != nil || day < 1 || day > 31 {
+				day = 0
+			}
+			date := data.NewDate(year, time.Month(month), day)
+			if day == 0 || date.Month() != time.Month(month) {
+				date = data.NewDate(year, time.Month(month), 1)
+			} else {
+				report.Mapped["Day"] = "release_date"
+			}
+			comics.ReleaseDate = &date
+			report.Mapped["Month"] = "release_date"
+		}
+	}
+	for _, field := range []string{"Month", "Day"} {
+		if info[field] != "" && report.Mapped[field] == "" {
+			report.Ignored = append(report.Ignored, field)
+		}
+	}
+
+	switch {
+	case info["PageCount"] != "":
+		pages, err := strconv.Atoi(info["PageCount"])
+		if err != nil {
+			v.AddError("pages", "must be an integer value")
+		}
+		comics.Pages = data.Pages(pages)
+		report.Mapped["PageCount"] = "pages"
+	case comics.Pages == 0:
+		comics.Pages = data.Pages(archive.PageCount())
+	}
+
+	if info["Summary"] != "" {
+		comics.Summary = info["Summary"]
+		report.Mapped["Summary"] = "summary"
+	}
+
+	// Credits from the archive are added to the ones the issue already has, so that
+	// roles ComicInfo.xml doesn't describe, such as inkers and colorists, are kept.
+	var credits []data.Credit
+	if !report.Created {
+		credits, err = app.models.Creators.GetCredits(comics.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	for _, role := range []struct {
+		field string
+		role  string
+	}{
+		{"Writer", data.RoleWriter},
+		{"Penciller", data.RolePenciller},
+	} {
+		if info[role.field] == "" {
+			continue
+		}
+		for _, name := range strings.Split(info[role.field], ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			creator, err := app.findOrCreateCreator(name)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			credits = addCredit(credits, data.Credit{CreatorID: creator.ID, Name: creator.Name, Role: role.role})
+		}
+		report.Mapped[role.field] = "credits"
+	}
+	comics.Credits = credits
+
+	if data.ValidateComics(v, comics); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Keep the archive itself so that owners can read the issue page by page. The first
+	// page becomes the cover; archives whose first page isn't a JPEG or PNG image simply
+	// keep their current cover. The files are stored while the issue is being saved, so
+	// that a failure leaves neither a half-imported issue nor one without its archive.
+	err = app.models.Comics.Import(comics, func(comics *data.Comics) error {
+		if archive.PageCount() == 0 {
+			return nil
+		}
+
+		err := app.storeArchive(comics, content)
+		if err != nil {
+			return err
+		}
+
+		page, err := archive.ReadPage(0)
+		if err != nil {
+			return err
+		}
+
+		urls, err := app.storeCover(comics.ID, page)
+		switch {
+		case errors.Is(err, errUnsupportedCoverType), errors.Is(err, errCoverTooLarge):
+			report.Ignored = append(report.Ignored, "cover")
+		case err != nil:
+			return err
+		default:
+			comics.CoverURLs = urls
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if report.Created {
+		status = http.StatusCreated
+		app.notifyHolds(comics.ID)
+	}
+
+	err = app.writeJSON(w, status, envelope{"comics": comics, "report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The addCredit() helper appends a credit unless the same creator already has the same
+// role.
+func addCredit(credits []data.Credit, credit data.Credit) []data.Credit {
+	for _, c := range credits {
+		if c.CreatorID == credit.CreatorID && c.Role == credit.Role {
+			return credits
+		}
+	}
+	return append(credits, credit)
+}
+
+// The findOrCreateSeries() helper returns the series with the given name and volume,
+// creating it first if the catalogue doesn't know it yet.
+func (app *application) findOrCreateSeries(name, volume, year string) (*data.Series, error) {
+	vol, _ := strconv.Atoi(volume)
+
+	series, err := app.models.Series.GetByName(name, int32(vol))
+	if err == nil {
+		return series, nil
+	}
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	series = &data.Series{
+		Name:      name,
+		Volume:    int32(vol),
+		StartYear: int32(time.Now().Year()),
+		Status:    "ongoing",
+	}
+	if series.Volume < 1 {
+		series.Volume = 1
+	}
+	if y, err := strconv.Atoi(year); err == nil && y >= 1888 && y <= time.Now().Year() {
+		series.StartYear = int32(y)
+	}
+
+	err = app.models.Series.Insert(series)
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// The findOrCreateCreator() helper returns the creator with the given name, creating it
+// first if needed.
+func (app *application) findOrCreateCreator(name string) (*data.Creator, error) {
+	creator, err := app.models.Creators.GetByName(name)
+	if err == nil {
+		return creator, nil
+	}
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	creator = &data.Creator{Name: name}
+	err = app.models.Creators.Insert(creator)
+	if err != nil {
+		return nil, err
+	}
+	return creator, nil
+}
+
+// The importShippingListHandler() imports a distributor shipping list uploaded as the
+// "file" form field: the issues on it are created or updated, and the shipped copies are
+// received into the inventory at the location given in the query string. With dry_run
+// set nothing is written and the report shows what the import would change. If any row
+// is invalid nothing is written either, and the report lists the errors of every row.
+func (app *application) importShippingListHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	location := app.readString(qs, "location", "store")
+	dryRun := app.readBool(qs, "dry_run", v)
+
+	v.Check(strings.TrimSpace(location) != "", "location", "must be provided")
+	v.Check(len(location) <= 100, "location", "must not be more than 100 bytes long")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	content, err := app.readFile(w, r, "file", maxShippingListBytes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rows, err := shipping.Parse(bytes.NewReader(content))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	hash := sha256.Sum256(content)
+	importer := &shipping.Importer{
+		Models:   app.models,
+		Location: location,
+		UserID:   &user.ID,
+		Note:     "shipping list",
+		ListHash: hash[:],
+	}
+
+	report, err := importer.Plan(rows)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !report.Valid() {
+		err = app.writeJSON(w, http.StatusUnprocessableEntity, envelope{"error": "the shipping list has invalid rows", "report": report}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if dryRun == nil || !*dryRun {
+		err = importer.Apply(report)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for _, result := range report.Rows {
+			if result.Action == shipping.ActionCreate {
+				app.notifyHolds(result.ComicsID)
+			}
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
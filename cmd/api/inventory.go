@@ -0,0 +1,326 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+)
+
+func (app *application) listInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	comics := app.readComics(w, r)
+	if comics == nil {
+		return
+	}
+
+	items, err := app.models.Inventory.GetAllForComics(comics.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"inventory": items}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	comics := app.readComics(w, r)
+	if comics == nil {
+		return
+	}
+
+	var input struct {
+		VariantID    *int64 `json:"variant_id"`
+		Location     string `json:"location"`
+		OnHand       int32  `json:"on_hand"`
+		ReorderPoint int32  `json:"reorder_point"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	item := &data.InventoryItem{
+		ComicsID:     comics.ID,
+		VariantID:    input.VariantID,
+		Location:     input.Location,
+		OnHand:       input.OnHand,
+		ReorderPoint: input.ReorderPoint,
+	}
+
+	v := validator.New()
+
+	if data.ValidateInventoryItem(v, item); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// A variant must belong to the issue it is stocked under.
+	if item.VariantID != nil {
+		found := false
+		for _, variant := range comics.Variants {
+			found = found || variant.ID == *item.VariantID
+		}
+		if !found {
+			v.AddError("variant_id", "must reference a variant of this issue")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	err = app.models.Inventory.Insert(item, &app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateInventory):
+			v.AddError("location", "this item is already stocked at this location")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/inventory/%d", item.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"inventory": item}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The readInventoryItem() helper loads the inventory item named by the :id URL
+// parameter. It sends the appropriate error response itself and returns nil if the
+// item can't be loaded.
+func (app *application) readInventoryItem(w http.ResponseWriter, r *http.Request) *data.InventoryItem {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	item, err := app.models.Inventory.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+	return item
+}
+
+func (app *application) showInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	item := app.readInventoryItem(w, r)
+	if item == nil {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"inventory": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	item := app.readInventoryItem(w, r)
+	if item == nil {
+		return
+	}
+
+	var input struct {
+		Location     *string `json:"location"`
+		ReorderPoint *int32  `json:"reorder_point"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Location != nil {
+		item.Location = *input.Location
+	}
+	if input.ReorderPoint != nil {
+		item.ReorderPoint = *input.ReorderPoint
+	}
+
+	v := validator.New()
+	if data.ValidateInventoryItem(v, item); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Inventory.Update(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateInventory):
+			v.AddError("location", "this item is already stocked at this location")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"inventory": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Inventory.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "inventory item successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAdjustmentsHandler(w http.ResponseWriter, r *http.Request) {
+	item := app.readInventoryItem(w, r)
+	if item == nil {
+		return
+	}
+
+	adjustments, err := app.models.Inventory.GetAdjustments(item.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"adjustments": adjustments}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The createAdjustmentHandler() changes the on-hand count of an item, for example when
+// a shipment arrives or copies are damaged, and records who made the change and why.
+func (app *application) createAdjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	item := app.readInventoryItem(w, r)
+	if item == nil {
+		return
+	}
+
+	var input struct {
+		Delta  int32  `json:"delta"`
+		Reason string `json:"reason"`
+		Note   string `json:"note"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	adjustment := &data.Adjustment{
+		UserID: &user.ID,
+		Delta:  input.Delta,
+		Reason: input.Reason,
+		Note:   input.Note,
+	}
+
+	v := validator.New()
+	if data.ValidateAdjustment(v, adjustment); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Inventory.Adjust(item, adjustment)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInsufficientStock):
+			app.insufficientStockResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"adjustment": adjustment, "inventory": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) reserveInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeReservation(w, r, app.models.Inventory.Reserve)
+}
+
+func (app *application) releaseInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeReservation(w, r, app.models.Inventory.Release)
+}
+
+// The changeReservation() helper reads the quantity from the request body and applies
+// the given reservation operation to the inventory item named by the :id URL parameter.
+func (app *application) changeReservation(w http.ResponseWriter, r *http.Request, op func(*data.InventoryItem, int32) error) {
+	item := app.readInventoryItem(w, r)
+	if item == nil {
+		return
+	}
+
+	var input struct {
+		Quantity int32 `json:"quantity"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateQuantity(v, input.Quantity); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = op(item, input.Quantity)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInsufficientStock):
+			app.insufficientStockResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"inventory": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"expvar"
 	"flag"
+	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/miras210/finalGolang/internal/data"
 	"github.com/miras210/finalGolang/internal/jsonlog"
 	"github.com/miras210/finalGolang/internal/mailer"
+	"github.com/miras210/finalGolang/internal/payments"
+	"github.com/miras210/finalGolang/internal/storage"
 	"os"
 	"runtime"
 	"strings"
@@ -48,14 +52,35 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	storage struct {
+		dir string
+		url string
+	}
+	payments struct {
+		provider      string
+		webhookSecret string
+	}
+	preorders struct {
+		notifyInterval time.Duration
+	}
+	wishlist struct {
+		notifyInterval time.Duration
+		notifyCooldown time.Duration
+	}
+	tokens struct {
+		accessTTL  time.Duration
+		refreshTTL time.Duration
+	}
 }
 
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config   config
+	logger   *jsonlog.Logger
+	models   data.Models
+	mailer   mailer.Mailer
+	storage  storage.BlobStore
+	payments payments.Provider
+	wg       sync.WaitGroup
 }
 
 func main() {
@@ -84,10 +109,31 @@ func main() {
 		return nil
 	})
 
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./uploads", "Directory for uploaded files")
+	flag.StringVar(&cfg.storage.url, "storage-url", "http://localhost:4000/media", "Public base URL of uploaded files")
+
+	flag.StringVar(&cfg.payments.provider, "payments-provider", "fake", "Payment provider (fake)")
+	flag.StringVar(&cfg.payments.webhookSecret, "payments-webhook-secret", os.Getenv("COMICS_STORE_PAYMENTS_WEBHOOK_SECRET"), "Payment webhook signing secret")
+
+	flag.DurationVar(&cfg.preorders.notifyInterval, "preorders-notify-interval", time.Hour, "How often to email customers whose pre-orders went on sale")
+
+	flag.DurationVar(&cfg.wishlist.notifyInterval, "wishlist-notify-interval", 5*time.Minute, "How often to check wished issues for new stock")
+	flag.DurationVar(&cfg.wishlist.notifyCooldown, "wishlist-notify-cooldown", 72*time.Hour, "Minimum time between back-in-stock emails about the same issue")
+
+	flag.DurationVar(&cfg.tokens.accessTTL, "access-token-ttl", 24*time.Hour, "Lifetime of authentication tokens")
+	flag.DurationVar(&cfg.tokens.refreshTTL, "refresh-token-ttl", 30*24*time.Hour, "Lifetime of refresh tokens")
+
 	flag.Parse()
 
 	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
 
+	if cfg.preorders.notifyInterval <= 0 {
+		logger.PrintFatal(errors.New("preorders notify interval must be greater than zero"), nil)
+	}
+	if cfg.wishlist.notifyInterval <= 0 {
+		logger.PrintFatal(errors.New("wishlist notify interval must be greater than zero"), nil)
+	}
+
 	db, err := openDB(cfg)
 	if err != nil {
 		logger.PrintFatal(err, nil)
@@ -111,6 +157,24 @@ func main() {
 	}
 	logger.PrintInfo("database migrations applied", nil)
 
+	store, err := storage.NewLocalStore(cfg.storage.dir, cfg.storage.url)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	var provider payments.Provider
+	switch cfg.payments.provider {
+	case "fake":
+		// Webhooks are trusted on their signature alone, so an empty secret would let
+		// anyone forge them.
+		if cfg.payments.webhookSecret == "" {
+			logger.PrintFatal(errors.New("payments webhook secret must be provided"), nil)
+		}
+		provider = payments.NewFake(cfg.payments.webhookSecret)
+	default:
+		logger.PrintFatal(fmt.Errorf("unknown payment provider %q", cfg.payments.provider), nil)
+	}
+
 	expvar.NewString("version").Set(version)
 
 	expvar.Publish("goroutines", expvar.Func(func() interface{} {
@@ -126,10 +190,12 @@ func main() {
 	}))
 
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:   cfg,
+		logger:   logger,
+		models:   data.NewModels(db),
+		mailer:   mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		storage:  store,
+		payments: provider,
 	}
 
 	err = app.serve()
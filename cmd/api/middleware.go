@@ -82,12 +82,25 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
+		// HTTP Basic credentials are only accepted from e-reader apps, on the routes
+		// wrapped by requireBasicAuth(), which checks them itself. Everywhere else the
+		// request stays anonymous and is refused by requireAuthenticatedCaller().
+		if _, _, ok := r.BasicAuth(); ok {
+			r = app.contextSetUser(r, data.AnonymousUser)
+			next.ServeHTTP(w, r)
+			return
+		}
 		headerParts := strings.Split(authorizationHeader, " ")
 		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
 		token := headerParts[1]
+		// Scripts send a personal API key in place of an authentication token.
+		if strings.HasPrefix(token, data.APIKeyPrefix) {
+			app.authenticateAPIKey(w, r, token, next)
+			return
+		}
 		v := validator.New()
 		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
 			app.invalidAuthenticationTokenResponse(w, r)
@@ -103,12 +116,57 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			}
 			return
 		}
+		err = app.models.Tokens.Touch(token)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
 		r = app.contextSetUser(r, user)
+		r = app.contextSetToken(r, token)
 		next.ServeHTTP(w, r)
 	})
 }
 
+// The authenticateAPIKey() helper finishes authenticate() for requests made with an API
+// key, adding both the user and the key to the request context.
+func (app *application) authenticateAPIKey(w http.ResponseWriter, r *http.Request, plaintext string, next http.Handler) {
+	v := validator.New()
+	if data.ValidateAPIKeyPlaintext(v, plaintext); !v.Valid() {
+		app.invalidAuthenticationTokenResponse(w, r)
+		return
+	}
+	key, user, err := app.models.APIKeys.GetForPlaintext(plaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.models.APIKeys.Touch(key.ID)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+	}
+	r = app.contextSetUser(r, user)
+	r = app.contextSetAPIKey(r, key)
+	next.ServeHTTP(w, r)
+}
+
+// Requests made with an API key are turned away by requireActivatedUser() and
+// requireAuthenticatedUser(), since a key may only do what its permissions allow. Only
+// requirePermission() lets them through.
 func (app *application) requireActivatedUser(next http.HandlerFunc) http.HandlerFunc {
+	return app.requireActivatedCaller(app.rejectAPIKey(next))
+}
+
+func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
+	return app.requireAuthenticatedCaller(app.rejectAPIKey(next))
+}
+
+// The requireActivatedCaller() middleware checks for an activated user like
+// requireActivatedUser(), but also accepts requests made with an API key.
+func (app *application) requireActivatedCaller(next http.HandlerFunc) http.HandlerFunc {
 	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
 		if !user.Activated {
@@ -117,13 +175,17 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 		}
 		next.ServeHTTP(w, r)
 	})
-	return app.requireAuthenticatedUser(fn)
+	return app.requireAuthenticatedCaller(fn)
 }
 
-func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
+func (app *application) requireAuthenticatedCaller(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
 		if user.IsAnonymous() {
+			if _, _, ok := r.BasicAuth(); ok {
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
 			app.authenticationRequiredResponse(w, r)
 			return
 		}
@@ -131,12 +193,78 @@ func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.Han
 	})
 }
 
+// The requireBasicAuth() middleware lets e-reader apps, which can't obtain a token, sign
+// in with the user's email address and password using HTTP Basic authentication. Anonymous
+// clients are sent a WWW-Authenticate: Basic challenge, since e-reader apps only prompt
+// the user for a login after they receive one.
+func (app *application) requireBasicAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !app.contextGetUser(r).IsAnonymous() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		email, plaintext, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", basicChallenge)
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, err := app.models.Users.GetByEmail(email)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.invalidBasicCredentialsResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		match, err := user.Password.Matches(plaintext)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !match {
+			app.invalidBasicCredentialsResponse(w, r)
+			return
+		}
+		r = app.contextSetUser(r, user)
+		next.ServeHTTP(w, r)
+	}
+}
+
+// The rejectAPIKey() middleware turns away requests made with an API key, so that a key
+// can't be used beyond its permissions, for instance to mint more keys or take over the
+// account.
+func (app *application) rejectAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.contextGetAPIKey(r) != nil {
+			app.apiKeyNotAllowedResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// The permissionsFor() helper returns the permission codes the current request may use.
+// These are the permissions of the user, limited to the permissions of the API key when
+// the request was made with one.
+func (app *application) permissionsFor(r *http.Request) (data.Permissions, error) {
+	permissions, err := app.models.Permissions.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		return nil, err
+	}
+	if key := app.contextGetAPIKey(r); key != nil {
+		permissions = permissions.Intersect(key.Permissions)
+	}
+	return permissions, nil
+}
+
 // Note that the first parameter for the middleware function is the permission code that
 // we require the user to have.
 func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
-		user := app.contextGetUser(r)
-		permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+		permissions, err := app.permissionsFor(r)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
@@ -147,7 +275,39 @@ func (app *application) requirePermission(code string, next http.HandlerFunc) ht
 		}
 		next.ServeHTTP(w, r)
 	}
-	return app.requireActivatedUser(fn)
+	return app.requireActivatedCaller(fn)
+}
+
+// The requireOwnership() middleware only lets a request through if the current user owns
+// a digital copy of the issue named by the :id URL parameter. Staff with the
+// "comics:write" permission can access every issue. It is meant to be wrapped by
+// requirePermission(), which takes care of authentication.
+func (app *application) requireOwnership(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+		user := app.contextGetUser(r)
+		owns, err := app.models.Ownership.Owns(user.ID, id)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !owns {
+			permissions, err := app.permissionsFor(r)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			if !permissions.Include("comics:write") {
+				app.notPermittedResponse(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	}
 }
 
 func (app *application) enableCORS(next http.Handler) http.Handler {
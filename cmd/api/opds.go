@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/opds"
+	"github.com/miras210/finalGolang/internal/validator"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+// opdsPageSize is the number of issues listed on each page of an acquisition feed.
+const opdsPageSize = 20
+
+// The opdsRootHandler() serves the navigation feed that e-reader apps start browsing
+// the catalog from.
+func (app *application) opdsRootHandler(w http.ResponseWriter, r *http.Request) {
+	feed := opds.NewFeed("urn:comics-store:root", "Comics Store", time.Now())
+	feed.Links = []opds.Link{
+		{Rel: opds.RelSelf, Href: "/opds", Type: opds.NavigationType},
+		{Rel: opds.RelStart, Href: "/opds", Type: opds.NavigationType},
+		{Rel: opds.RelSearch, Href: "/opds/opensearch.xml", Type: opds.OpenSearchType},
+	}
+	feed.Entries = []opds.Entry{
+		{
+			ID:      "urn:comics-store:all",
+			Title:   "All comics",
+			Updated: feed.Updated,
+			Content: &opds.Content{Type: "text", Text: "Every issue in the catalog, newest first."},
+			Links: []opds.Link{
+				{Rel: opds.RelSubsection, Href: "/opds/all", Type: opds.AcquisitionType},
+			},
+		},
+	}
+
+	err := app.writeXML(w, http.StatusOK, feed, opds.NavigationType)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The opdsAllHandler() serves an acquisition feed of the whole catalog, newest first.
+func (app *application) opdsAllHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeAcquisitionFeed(w, r, "urn:comics-store:all", "All comics", "/opds/all", "")
+}
+
+// The opdsSearchHandler() serves an acquisition feed of the issues whose title matches
+// the q query string parameter.
+func (app *application) opdsSearchHandler(w http.ResponseWriter, r *http.Request) {
+	q := app.readString(r.URL.Query(), "q", "")
+	app.writeAcquisitionFeed(w, r, "urn:comics-store:search", fmt.Sprintf("Search results for %q", q), "/opds/search", q)
+}
+
+func (app *application) opdsOpenSearchHandler(w http.ResponseWriter, r *http.Request) {
+	description := opds.OpenSearchDescription{
+		ShortName:     "Comics Store",
+		Description:   "Search the Comics Store catalog by title",
+		InputEncoding: "UTF-8",
+		URLs: []opds.OpenSearchURL{
+			{Type: opds.AcquisitionType, Template: "/opds/search?q={searchTerms}"},
+		},
+	}
+
+	err := app.writeXML(w, http.StatusOK, description, opds.OpenSearchType)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The writeAcquisitionFeed() helper lists one page of issues matching the title as an
+// acquisition feed. The pagination metadata returned by ComicsModel.GetAll() is mapped
+// to first, previous, next and last links.
+func (app *application) writeAcquisitionFeed(w http.ResponseWriter, r *http.Request, id, title, feedPath, q string) {
+	v := validator.New()
+
+	filters := data.Filters{
+		Page:         app.readInt(r.URL.Query(), "page", 1, v),
+		PageSize:     opdsPageSize,
+		Sort:         "-id",
+		SortSafelist: []string{"-id"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	comics, metadata, err := app.models.Comics.GetAll(data.ComicsFilter{Title: q}, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	pageURL := func(page int) string {
+		qs := url.Values{}
+		if q != "" {
+			qs.Set("q", q)
+		}
+		if page > 1 {
+			qs.Set("page", fmt.Sprint(page))
+		}
+		if len(qs) == 0 {
+			return feedPath
+		}
+		return feedPath + "?" + qs.Encode()
+	}
+
+	feed := opds.NewFeed(id, title, time.Now())
+	feed.TotalResults = metadata.TotalRecords
+	feed.ItemsPerPage = filters.PageSize
+	feed.StartIndex = (filters.Page-1)*filters.PageSize + 1
+	feed.Links = []opds.Link{
+		{Rel: opds.RelSelf, Href: pageURL(filters.Page), Type: opds.AcquisitionType},
+		{Rel: opds.RelStart, Href: "/opds", Type: opds.NavigationType},
+		{Rel: opds.RelUp, Href: "/opds", Type: opds.NavigationType},
+		{Rel: opds.RelSearch, Href: "/opds/opensearch.xml", Type: opds.OpenSearchType},
+	}
+	if metadata.TotalRecords > 0 {
+		feed.Links = append(feed.Links,
+			opds.Link{Rel: opds.RelFirst, Href: pageURL(metadata.FirstPage), Type: opds.AcquisitionType},
+			opds.Link{Rel: opds.RelLast, Href: pageURL(metadata.LastPage), Type: opds.AcquisitionType},
+		)
+		if metadata.CurrentPage > metadata.FirstPage {
+			feed.Links = append(feed.Links, opds.Link{Rel: opds.RelPrevious, Href: pageURL(metadata.CurrentPage - 1), Type: opds.AcquisitionType})
+		}
+		if metadata.CurrentPage < metadata.LastPage {
+			feed.Links = append(feed.Links, opds.Link{Rel: opds.RelNext, Href: pageURL(metadata.CurrentPage + 1), Type: opds.AcquisitionType})
+		}
+	}
+
+	for _, c := range comics {
+		feed.Entries = append(feed.Entries, comicsEntry(c))
+	}
+
+	err = app.writeXML(w, http.StatusOK, feed, opds.AcquisitionType)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The comicsEntry() helper turns a comics issue into an OPDS publication entry. Issues
+// without a stored archive are listed without an acquisition link.
+func comicsEntry(c *data.Comics) opds.Entry {
+	entry := opds.Entry{
+		ID:      fmt.Sprintf("urn:comics-store:comics:%d", c.ID),
+		Title:   c.Title,
+		Updated: c.CreatedAt.UTC(),
+	}
+	if c.Summary != "" {
+		entry.Content = &opds.Content{Type: "text", Text: c.Summary}
+	}
+
+	if c.ArchiveKey != "" {
+		entry.Links = append(entry.Links, opds.Link{
+			Rel:  opds.RelAcquisition,
+			Href: fmt.Sprintf("/v1/comics/%d/archive", c.ID),
+			Type: opds.CBZType,
+		})
+	}
+	if href, ok := c.CoverURLs["large"]; ok {
+		entry.Links = append(entry.Links, opds.Link{Rel: opds.RelImage, Href: href, Type: imageType(href)})
+	}
+	if href, ok := c.CoverURLs["small"]; ok {
+		entry.Links = append(entry.Links, opds.Link{Rel: opds.RelThumbnail, Href: href, Type: imageType(href)})
+	}
+	return entry
+}
+
+// The imageType() helper guesses the content type of a cover from its URL.
+func imageType(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return mime.TypeByExtension(path.Ext(u.Path))
+}
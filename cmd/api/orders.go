@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+)
+
+// The checkoutHandler() turns the current user's cart into a pending order.
+func (app *application) checkoutHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	order, err := app.models.Orders.Checkout(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEmptyCart):
+			app.failedValidationResponse(w, r, map[string]string{"cart": "must contain at least one item"})
+		case errors.Is(err, data.ErrInsufficientStock):
+			app.insufficientStockResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/users/me/orders/%d", order.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"order": order}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The listOrders() helper lists orders, reading the status and pagination parameters
+// from the query string. A userID of -1 lists the orders of all users.
+func (app *application) listOrders(w http.ResponseWriter, r *http.Request, userID int64) {
+	var input struct {
+		Status string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Status = app.readString(qs, "status", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-id")
+
+	input.Filters.SortSafelist = []string{"id", "total", "-id", "-total"}
+
+	if input.Status != "" {
+		v.Check(validator.In(input.Status, data.OrderStatuses...), "status", "must be a valid order status")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	orders, metadata, err := app.models.Orders.GetAll(userID, input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"orders": orders, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listUserOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	app.listOrders(w, r, app.contextGetUser(r).ID)
+}
+
+func (app *application) listOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	userID := app.readInt(r.URL.Query(), "user_id", -1, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	app.listOrders(w, r, int64(userID))
+}
+
+// The readOrder() helper loads the order named by the :id URL parameter. Unless ownOnly
+// is false, orders of other users are reported as not found. It sends the appropriate
+// error response itself and returns nil if the order can't be used.
+func (app *application) readOrder(w http.ResponseWriter, r *http.Request, ownOnly bool) *data.Order {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	order, err := app.models.Orders.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	if ownOnly && (order.UserID == nil || *order.UserID != app.contextGetUser(r).ID) {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+	return order
+}
+
+func (app *application) showUserOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order := app.readOrder(w, r, true)
+	if order == nil {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order := app.readOrder(w, r, false)
+	if order == nil {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The cancelUserOrderHandler() lets customers cancel their own orders while they are
+// still pending. Any later change of status is up to the staff.
+func (app *application) cancelUserOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order := app.readOrder(w, r, true)
+	if order == nil {
+		return
+	}
+
+	if order.Status != data.OrderPending {
+		app.failedValidationResponse(w, r, map[string]string{"status": "only pending orders can be cancelled"})
+		return
+	}
+
+	app.changeOrderStatus(w, r, order, data.OrderCancelled)
+}
+
+func (app *application) updateOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order := app.readOrder(w, r, false)
+	if order == nil {
+		return
+	}
+
+	var input struct {
+		Status string `json:"status"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if v.Check(validator.In(input.Status, data.OrderStatuses...), "status", "must be a valid order status"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	app.changeOrderStatus(w, r, order, input.Status)
+}
+
+func (app *application) changeOrderStatus(w http.ResponseWriter, r *http.Request, order *data.Order, status string) {
+	err := app.models.Orders.UpdateStatus(order, status, &app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidTransition):
+			message := fmt.Sprintf("cannot move a %s order to %s", order.Status, status)
+			app.failedValidationResponse(w, r, map[string]string{"status": message})
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
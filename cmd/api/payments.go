@@ -0,0 +1,396 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/payments"
+	"github.com/miras210/finalGolang/internal/validator"
+	"io"
+	"net/http"
+)
+
+// maxWebhookBytes is the largest webhook request body we accept from the provider.
+const maxWebhookBytes = 1 << 20
+
+// The createPaymentHandler() pays for one of the current user's pending orders. The
+// order total is authorized and captured straight away; a declined card is recorded as
+// a declined payment and reported with a 402 Payment Required response.
+func (app *application) createPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		OrderID int64 `json:"order_id"`
+		Card    struct {
+			Number   string `json:"number"`
+			ExpMonth int    `json:"exp_month"`
+			ExpYear  int    `json:"exp_year"`
+			CVC      string `json:"cvc"`
+		} `json:"card"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.OrderID > 0, "order_id", "must be provided")
+	v.Check(input.Card.Number != "", "card", "number must be provided")
+	v.Check(input.Card.CVC != "", "card", "cvc must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	order, err := app.models.Orders.Get(input.OrderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("order_id", "must reference one of your orders")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if order.UserID == nil || *order.UserID != user.ID {
+		v.AddError("order_id", "must reference one of your orders")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if order.Status != data.OrderPending {
+		v.AddError("order_id", "must reference an order awaiting payment")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	card := payments.Card{
+		Number:   input.Card.Number,
+		ExpMonth: input.Card.ExpMonth,
+		ExpYear:  input.Card.ExpYear,
+		CVC:      input.Card.CVC,
+	}
+
+	// The payment is recorded as pending before the provider is asked, which claims the
+	// order: a concurrent request for the same order fails to insert its own payment.
+	payment := &data.Payment{
+		UserID:    &user.ID,
+		OrderID:   order.ID,
+		Amount:    order.Total,
+		Currency:  "USD",
+		Provider:  app.payments.Name(),
+		Status:    payments.StatusPending,
+		CardLast4: card.Last4(),
+	}
+
+	err = app.models.Payments.Insert(payment)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicatePayment):
+			app.paymentInProgressResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	result, err := app.payments.Authorize(int64(order.Total), "USD", card)
+	if err != nil {
+		payment.Status = payments.StatusFailed
+		if err := app.models.Payments.UpdateStatus(payment); err != nil {
+			app.logError(r, err)
+		}
+		switch {
+		case errors.Is(err, payments.ErrInvalidCard):
+			v.AddError("card", "must be a valid, unexpired card")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	payment.Reference = result.Reference
+	payment.Status = result.Status
+	payment.DeclineCode = result.DeclineCode
+	err = app.models.Payments.UpdateStatus(payment)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if payment.Status == payments.StatusDeclined {
+		err = app.writeJSON(w, http.StatusPaymentRequired, envelope{"payment": payment}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	result, err = app.payments.Capture(payment.Reference, int64(payment.Amount))
+	if err != nil {
+		payment.Status = payments.StatusFailed
+		if err := app.models.Payments.UpdateStatus(payment); err != nil {
+			app.logError(r, err)
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	payment.Status = result.Status
+	err = app.models.Payments.UpdateStatus(payment)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.syncOrderWithPayment(order, payment, &user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/payments/%d", payment.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"payment": payment, "order": order}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The syncOrderWithPayment() helper moves an order along after the status of one of its
+// payments changed: a captured payment marks a pending order as paid, and a refunded
+// payment refunds the order. Orders that can't make the move are left alone.
+func (app *application) syncOrderWithPayment(order *data.Order, payment *data.Payment, userID *int64) error {
+	var status string
+	switch payment.Status {
+	case payments.StatusCaptured:
+		status = data.OrderPaid
+	case payments.StatusRefunded:
+		status = data.OrderRefunded
+	default:
+		return nil
+	}
+
+	if !order.CanTransition(status) {
+		return nil
+	}
+	return app.models.Orders.UpdateStatus(order, status, userID)
+}
+
+func (app *application) listPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		OrderID int
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.OrderID = app.readInt(qs, "order_id", -1, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-id")
+
+	input.Filters.SortSafelist = []string{"id", "amount", "-id", "-amount"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	payments, metadata, err := app.models.Payments.GetAll(app.contextGetUser(r).ID, int64(input.OrderID), input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"payments": payments, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	payment, err := app.models.Payments.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if payment.UserID == nil || *payment.UserID != app.contextGetUser(r).ID {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"payment": payment}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The refundPaymentHandler() returns the full amount of a captured payment to the
+// customer and refunds the order it paid for.
+func (app *application) refundPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	payment, err := app.models.Payments.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if payment.Status != payments.StatusCaptured || payment.Provider != app.payments.Name() {
+		app.failedValidationResponse(w, r, map[string]string{"status": "only captured payments can be refunded"})
+		return
+	}
+
+	result, err := app.payments.Refund(payment.Reference, int64(payment.Amount))
+	if err != nil {
+		switch {
+		case errors.Is(err, payments.ErrInvalidState), errors.Is(err, payments.ErrUnknownReference):
+			app.failedValidationResponse(w, r, map[string]string{"status": "the provider can't refund this payment"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	payment.Status = result.Status
+	err = app.models.Payments.UpdateStatus(payment)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	order, err := app.models.Orders.Get(payment.OrderID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.syncOrderWithPayment(order, payment, &app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"payment": payment, "order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The paymentWebhookHandler() receives status changes from the payment provider. The
+// request is only trusted if its Payments-Signature header matches the body, and only
+// status changes allowed by payments.CanTransition() are applied.
+func (app *application) paymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBytes)
+
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, err := app.payments.VerifyWebhook(payload, r.Header.Get("Payments-Signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, payments.ErrInvalidSignature):
+			app.errorResponse(w, r, http.StatusUnauthorized, "invalid webhook signature")
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	statuses := []string{payments.StatusAuthorized, payments.StatusCaptured, payments.StatusDeclined, payments.StatusRefunded, payments.StatusFailed}
+	if v.Check(validator.In(event.Status, statuses...), "status", "must be a valid payment status"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	payment, err := app.models.Payments.GetByReference(app.payments.Name(), event.Reference)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Events are delivered at least once and in no particular order, so repeated and
+	// stale events are acknowledged without changing anything. Orders are protected the
+	// same way by Order.CanTransition(), so they never leave refunded or cancelled.
+	switch {
+	case payment.Status == event.Status:
+	case !payments.CanTransition(payment.Status, event.Status):
+		app.logger.PrintInfo("payment webhook event ignored", map[string]string{
+			"reference": event.Reference,
+			"from":      payment.Status,
+			"to":        event.Status,
+		})
+	default:
+		payment.Status = event.Status
+		err = app.models.Payments.UpdateStatus(payment)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		order, err := app.models.Orders.Get(payment.OrderID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.syncOrderWithPayment(order, payment, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "event processed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
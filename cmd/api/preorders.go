@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+	"time"
+)
+
+// preordersNotifyBatch is the most pre-order emails sent in one run of the notifier.
+// Anything left over is picked up by the next run.
+const preordersNotifyBatch = 100
+
+func (app *application) listUserPreordersHandler(w http.ResponseWriter, r *http.Request) {
+	preorders, err := app.models.Preorders.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"preorders": preorders}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The createPreorderHandler() pre-orders copies of an upcoming issue for the current
+// user. Issues can't be pre-ordered once their final order cutoff has passed, since the
+// shop can no longer add them to its order with the distributor.
+func (app *application) createPreorderHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ComicsID int64  `json:"comics_id"`
+		Quantity *int32 `json:"quantity"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	preorder := &data.Preorder{
+		UserID:   app.contextGetUser(r).ID,
+		ComicsID: input.ComicsID,
+		Quantity: 1,
+	}
+	if input.Quantity != nil {
+		preorder.Quantity = *input.Quantity
+	}
+
+	v := validator.New()
+
+	comics, err := app.models.Comics.Get(input.ComicsID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("comics_id", "must reference an existing issue")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	preorder.Title = comics.Title
+	preorder.FOCDate = comics.FOCDate
+	preorder.OnSaleDate = comics.StreetDate()
+
+	if data.ValidatePreorder(v, preorder); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Preorders.Insert(preorder)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicatePreorder):
+			v.AddError("comics_id", "you have already pre-ordered this issue")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/users/me/preorders/%d", preorder.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"preorder": preorder}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The updatePreorderHandler() changes the number of copies pre-ordered. Like new
+// pre-orders, this is only allowed until the final order cutoff of the issue.
+func (app *application) updatePreorderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	preorder, err := app.models.Preorders.Get(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Quantity *int32 `json:"quantity"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Quantity != nil {
+		preorder.Quantity = *input.Quantity
+	}
+
+	v := validator.New()
+	if data.ValidatePreorder(v, preorder); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Preorders.Update(preorder)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"preorder": preorder}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deletePreorderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	preorder, err := app.models.Preorders.Get(user.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !preorder.Open(data.Today()) {
+		v := validator.New()
+		v.AddError("preorder", "can't be cancelled after the final order cutoff")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Preorders.Delete(user.ID, preorder.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "pre-order successfully cancelled"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The preorderSummaryHandler() shows staff how many customers pre-ordered each upcoming
+// issue and how many copies they want in total.
+func (app *application) preorderSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	summaries, err := app.models.Preorders.GetSummary()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"preorders": summaries}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The notifyPreorders() helper periodically emails customers whose pre-ordered issues
+// have gone on sale, until stop is closed. A pre-order is only marked as notified once
+// its email was sent, so failed emails are retried on the next run.
+func (app *application) notifyPreorders(stop <-chan struct{}) {
+	ticker := time.NewTicker(app.config.preorders.notifyInterval)
+	defer ticker.Stop()
+
+	for {
+		app.sendPreorderNotifications()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (app *application) sendPreorderNotifications() {
+	preorders, err := app.models.Preorders.GetDue(data.Today(), preordersNotifyBatch)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+
+	for _, preorder := range preorders {
+		mailData := map[string]interface{}{
+			"name":       preorder.UserName,
+			"title":      preorder.Title,
+			"onSaleDate": preorder.OnSaleDate,
+			"quantity":   preorder.Quantity,
+		}
+		err = app.mailer.Send(preorder.UserEmail, "preorder_on_sale.tmpl", mailData)
+		if err == nil {
+			err = app.models.Preorders.MarkNotified(preorder)
+		}
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"preorder_id": fmt.Sprint(preorder.ID),
+				"user_id":     fmt.Sprint(preorder.UserID),
+			})
+		}
+	}
+}
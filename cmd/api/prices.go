@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+)
+
+// The listPriceHistoryHandler() returns the price history of an issue and its variants.
+// The variant_id query parameter limits it to a single variant, or to the issue itself
+// when set to 0, and from and to limit it to a date range.
+func (app *application) listPriceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	comics, err := app.models.Comics.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	variantID := int64(app.readInt(qs, "variant_id", -1, v))
+	from := app.readDate(qs, "from", v)
+	to := app.readDate(qs, "to", v)
+
+	if variantID > 0 {
+		found := false
+		for _, variant := range comics.Variants {
+			if variant.ID == variantID {
+				found = true
+				break
+			}
+		}
+		v.Check(found, "variant_id", "must reference a variant of this issue")
+	}
+	if from != nil && to != nil {
+		v.Check(!from.After(to.Time), "to", "must not be before from")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	prices, err := app.models.Prices.GetForComics(comics.ID, variantID, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"prices": prices}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
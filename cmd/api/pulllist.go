@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+)
+
+func (app *application) listPullListHandler(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := app.models.PullList.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"pulllist": subscriptions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The createPullListHandler() adds a series to the pull list of the current user. New
+// issues of the series are held for the user from then on.
+func (app *application) createPullListHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		SeriesName string `json:"series_name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	subscription := &data.Subscription{
+		UserID:     app.contextGetUser(r).ID,
+		SeriesName: input.SeriesName,
+		SeriesKey:  data.NormalizeSeriesName(input.SeriesName),
+	}
+
+	v := validator.New()
+	if data.ValidateSubscription(v, subscription); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.PullList.Insert(subscription)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSubscription):
+			v.AddError("series_name", "this series is already on your pull list")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"subscription": subscription}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deletePullListHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.PullList.Delete(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "series successfully removed from pull list"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listHoldsHandler(w http.ResponseWriter, r *http.Request) {
+	holds, err := app.models.PullList.GetHoldsForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"holds": holds}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) releaseHoldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.PullList.DeleteHold(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "hold successfully released"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The notifyHolds() helper emails every user an issue was held for when it was created.
+// The emails are sent in the background, so a slow mail server doesn't hold up the
+// request that created the issue.
+func (app *application) notifyHolds(comicsID int64) {
+	app.background(func() {
+		holds, err := app.models.PullList.GetHoldsForComics(comicsID)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		for _, hold := range holds {
+			mailData := map[string]interface{}{
+				"name":        hold.UserName,
+				"title":       hold.Title,
+				"issueNumber": hold.IssueNumber,
+			}
+			err = app.mailer.Send(hold.UserEmail, "pulllist_hold.tmpl", mailData)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"comics_id": fmt.Sprint(comicsID),
+					"user_id":   fmt.Sprint(hold.UserID),
+				})
+			}
+		}
+	})
+}
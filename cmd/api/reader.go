@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/cbz"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/storage"
+	"image"
+	_ "image/gif"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+)
+
+var errNoArchive = errors.New("no archive stored")
+
+// The archiveKey() helper returns the blob store key of an issue's digital archive.
+func archiveKey(comicsID int64) string {
+	return fmt.Sprintf("archives/%d.cbz", comicsID)
+}
+
+// The storeArchive() helper checks that an uploaded file is a readable CBZ archive with
+// at least one page, and writes it to the blob store.
+func (app *application) storeArchive(comics *data.Comics, content []byte) error {
+	archive, err := cbz.Open(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return err
+	}
+	if archive.PageCount() == 0 {
+		return fmt.Errorf("%w: archive must contain at least one page image", cbz.ErrInvalidArchive)
+	}
+
+	key := archiveKey(comics.ID)
+	err = app.storage.Put(key, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	comics.ArchiveKey = key
+	return nil
+}
+
+// The openArchive() helper opens the stored archive of an issue. The caller must close
+// the returned object once it is done with the archive.
+func (app *application) openArchive(comics *data.Comics) (*cbz.Archive, storage.Object, error) {
+	if comics.ArchiveKey == "" {
+		return nil, nil, errNoArchive
+	}
+
+	object, err := app.storage.Open(comics.ArchiveKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, errNoArchive
+		}
+		return nil, nil, err
+	}
+
+	size, err := object.Seek(0, io.SeekEnd)
+	if err != nil {
+		object.Close()
+		return nil, nil, err
+	}
+
+	archive, err := cbz.Open(object, size)
+	if err != nil {
+		object.Close()
+		return nil, nil, err
+	}
+	return archive, object, nil
+}
+
+// The readComics() helper loads the issue named by the :id URL parameter. It sends the
+// appropriate error response itself and returns nil if the issue can't be loaded.
+func (app *application) readComics(w http.ResponseWriter, r *http.Request) *data.Comics {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	comics, err := app.models.Comics.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+	return comics
+}
+
+func (app *application) updateComicsArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	comics := app.readComics(w, r)
+	if comics == nil {
+		return
+	}
+
+	content, err := app.readFile(w, r, "file", maxArchiveBytes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = app.storeArchive(comics, content)
+	if err != nil {
+		switch {
+		case errors.Is(err, cbz.ErrInvalidArchive):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Comics.Update(comics)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "archive successfully stored"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showComicsArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	comics := app.readComics(w, r)
+	if comics == nil {
+		return
+	}
+
+	if comics.ArchiveKey == "" {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	object, err := app.storage.Open(comics.ArchiveKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer object.Close()
+
+	name := fmt.Sprintf("comics-%d.cbz", comics.ID)
+	w.Header().Set("Content-Type", "application/vnd.comicbook+zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("ETag", fmt.Sprintf(`"%d-%d"`, comics.ID, comics.Version))
+
+	http.ServeContent(w, r, name, time.Time{}, object)
+}
+
+// pageInfo describes a single page of an issue in the reader manifest.
+type pageInfo struct {
+	Number      int    `json:"number"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+}
+
+// The showPagesManifestHandler() lists the pages of an issue's archive with their
+// content type and, where the image format is understood, their dimensions.
+func (app *application) showPagesManifestHandler(w http.ResponseWriter, r *http.Request) {
+	comics := app.readComics(w, r)
+	if comics == nil {
+		return
+	}
+
+	archive, object, err := app.openArchive(comics)
+	if err != nil {
+		switch {
+		case errors.Is(err, errNoArchive):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer object.Close()
+
+	pages := make([]pageInfo, 0, archive.PageCount())
+	for i := 0; i < archive.PageCount(); i++ {
+		rc, name, err := archive.Page(i)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		page := pageInfo{
+			Number:      i + 1,
+			ContentType: mime.TypeByExtension(path.Ext(name)),
+		}
+		if config, _, err := image.DecodeConfig(rc); err == nil {
+			page.Width = config.Width
+			page.Height = config.Height
+		}
+		rc.Close()
+
+		pages = append(pages, page)
+	}
+
+	env := envelope{
+		"comics_id":  comics.ID,
+		"page_count": len(pages),
+		"pages":      pages,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The showPageHandler() streams the n-th page (counting from 1) of an issue's archive.
+// http.ServeContent() takes care of range requests and conditional requests against the
+// ETag, which only changes when the issue is updated.
+func (app *application) showPageHandler(w http.ResponseWriter, r *http.Request) {
+	comics := app.readComics(w, r)
+	if comics == nil {
+		return
+	}
+
+	n, err := app.readNamedIDParam(r, "n")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	archive, object, err := app.openArchive(comics)
+	if err != nil {
+		switch {
+		case errors.Is(err, errNoArchive):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer object.Close()
+
+	rc, name, err := archive.Page(int(n) - 1)
+	if err != nil {
+		switch {
+		case errors.Is(err, cbz.ErrPageNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, cbz.ErrPageTooLarge):
+			app.pageTooLargeResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	content, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if len(content) > cbz.MaxPageBytes {
+		app.pageTooLargeResponse(w, r)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("ETag", fmt.Sprintf(`"%d-%d-%d"`, comics.ID, comics.Version, n))
+
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(content))
+}
+
+func (app *application) createOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	comics := app.readComics(w, r)
+	if comics == nil {
+		return
+	}
+
+	var input struct {
+		UserID int64 `json:"user_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.UserID < 1 {
+		app.failedValidationResponse(w, r, map[string]string{"user_id": "must be a positive integer"})
+		return
+	}
+
+	err = app.models.Ownership.Grant(input.UserID, comics.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnknownUser):
+			app.failedValidationResponse(w, r, map[string]string{"user_id": "must reference an existing user"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"message": "digital copy successfully granted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	comicsID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	userID, err := app.readNamedIDParam(r, "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Ownership.Revoke(userID, comicsID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "digital copy successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
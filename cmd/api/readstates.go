@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+)
+
+// maxReadStates is the most read states a single bulk update can change.
+const maxReadStates = 100
+
+func (app *application) listReadStatesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	status := app.readString(r.URL.Query(), "status", "")
+	if status != "" {
+		v.Check(validator.In(status, data.ReadReading, data.ReadRead), "status", "must be reading or read")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	states, err := app.models.ReadStates.GetAllForUser(app.contextGetUser(r).ID, status)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"read_states": states}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The updateReadStatesHandler() changes the read state of several issues at once, so
+// that reader apps can sync after being offline. Either every state is saved or, if any
+// of them is invalid, none is; validation errors are keyed by the position of the state
+// in the request, such as "states[2].last_page".
+func (app *application) updateReadStatesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		States []struct {
+			ComicsID int64      `json:"comics_id"`
+			Status   string     `json:"status"`
+			LastPage int32      `json:"last_page"`
+			ReadDate *data.Date `json:"read_date"`
+		} `json:"states"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.States) > 0, "states", "must contain at least 1 read state")
+	v.Check(len(input.States) <= maxReadStates, "states", fmt.Sprintf("must not contain more than %d read states", maxReadStates))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ids := make([]int64, len(input.States))
+	for i, in := range input.States {
+		ids[i] = in.ComicsID
+	}
+
+	pages, err := app.models.Comics.GetPageCounts(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	states := make([]*data.ReadState, len(input.States))
+	seen := make(map[int64]int)
+	for i, in := range input.States {
+		state := &data.ReadState{
+			ComicsID: in.ComicsID,
+			Status:   in.Status,
+			LastPage: in.LastPage,
+			ReadDate: in.ReadDate,
+		}
+		states[i] = state
+
+		key := fmt.Sprintf("states[%d].", i)
+
+		count, ok := pages[in.ComicsID]
+		if !ok {
+			v.AddError(key+"comics_id", "must reference an existing issue")
+			continue
+		}
+		if j, ok := seen[in.ComicsID]; ok {
+			v.AddError(key+"comics_id", fmt.Sprintf("must not repeat states[%d]", j))
+			continue
+		}
+		seen[in.ComicsID] = i
+
+		sv := validator.New()
+		data.ValidateReadState(sv, state, count)
+		for field, message := range sv.Errors {
+			v.AddError(key+field, message)
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ReadStates.SetAll(app.contextGetUser(r).ID, states)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"read_states": states}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) continueReadingHandler(w http.ResponseWriter, r *http.Request) {
+	issues, err := app.models.ReadStates.ContinueReading(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"continue_reading": issues}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/ical"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// calendarWeeks is how many weeks ahead the release calendar looks.
+const calendarWeeks = 12
+
+// The listReleasesHandler() answers "what's out this week?". It lists the issues going
+// on sale in the ISO week given by the week query string parameter, such as 2026-W42,
+// grouped by publisher. Without a week parameter the current week is listed.
+func (app *application) listReleasesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	monday := data.Today().StartOfWeek()
+
+	if week := app.readString(r.URL.Query(), "week", ""); week != "" {
+		var err error
+		monday, err = data.ParseWeek(week)
+		if err != nil {
+			v.AddError("week", "must be an ISO week such as 2026-W42")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	releases, err := app.models.Comics.GetReleases(monday, monday.AddDays(7))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"week":          monday.Week(),
+		"start_date":    monday,
+		"end_date":      monday.AddDays(6),
+		"new_comic_day": monday.AddDays(2),
+		"releases":      releases,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The releasesCalendarHandler() serves the upcoming releases as an iCalendar feed that
+// calendar apps can subscribe to. Every issue is an all-day event on the day it goes on
+// sale, from the start of the current week until calendarWeeks weeks ahead.
+func (app *application) releasesCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	from := data.Today().StartOfWeek()
+	to := from.AddDays(7 * calendarWeeks)
+
+	releases, err := app.models.Comics.GetReleases(from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	calendar := ical.Calendar{
+		ProdID:          "-//Comics Store//Releases//EN",
+		Name:            "Comics Store releases",
+		RefreshInterval: 12 * time.Hour,
+	}
+
+	for _, group := range releases {
+		for _, comics := range group.Comics {
+			calendar.Events = append(calendar.Events, releaseEvent(r, group, comics))
+		}
+	}
+
+	var buf bytes.Buffer
+	_, err = calendar.WriteTo(&buf)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Content-Disposition", `inline; filename="releases.ics"`)
+	w.Write(buf.Bytes())
+}
+
+// The releaseEvent() helper turns an issue into a calendar event on its street date.
+func releaseEvent(r *http.Request, group *data.ReleaseGroup, comics *data.Comics) ical.Event {
+	summary := comics.Title
+	if comics.IssueNumber != "" {
+		summary = fmt.Sprintf("%s #%s", comics.Title, comics.IssueNumber)
+	}
+
+	var details []string
+	if group.Publisher != "" {
+		details = append(details, group.Publisher)
+	}
+	if comics.Price > 0 {
+		details = append(details, "$"+comics.Price.String())
+	}
+	if comics.FOCDate != nil {
+		details = append(details, "Final order cutoff: "+comics.FOCDate.String())
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return ical.Event{
+		UID:         fmt.Sprintf("comics-%d@%s", comics.ID, r.Host),
+		Date:        comics.StreetDate().Time,
+		Summary:     summary,
+		Description: strings.Join(details, "\n"),
+		URL:         fmt.Sprintf("%s://%s/v1/comics/%d", scheme, r.Host, comics.ID),
+		Updated:     comics.CreatedAt,
+	}
+}
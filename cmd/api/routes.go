@@ -4,6 +4,7 @@ import (
 	"expvar"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
+	"path/filepath"
 )
 
 func (app *application) routes() http.Handler {
@@ -20,12 +21,142 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodPatch, "/v1/comics/:id", app.requirePermission("comics:write", app.updateComicsHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/comics/:id", app.requirePermission("comics:write", app.deleteComicsHandler))
 
+	router.HandlerFunc(http.MethodPut, "/v1/comics/:id/cover", app.requirePermission("comics:write", app.updateComicsCoverHandler))
+
+	router.HandlerFunc(http.MethodPut, "/v1/comics/:id/archive", app.requirePermission("comics:write", app.updateComicsArchiveHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/comics/:id/archive", app.requireBasicAuth(app.requirePermission("comics:read", app.requireOwnership(app.showComicsArchiveHandler))))
+	router.HandlerFunc(http.MethodGet, "/v1/comics/:id/pages", app.requireBasicAuth(app.requirePermission("comics:read", app.requireOwnership(app.showPagesManifestHandler))))
+	router.HandlerFunc(http.MethodGet, "/v1/comics/:id/pages/:n", app.requireBasicAuth(app.requirePermission("comics:read", app.requireOwnership(app.showPageHandler))))
+
+	router.HandlerFunc(http.MethodPost, "/v1/comics/:id/owners", app.requirePermission("comics:write", app.createOwnerHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/comics/:id/owners/:user_id", app.requirePermission("comics:write", app.deleteOwnerHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/comics/:id/variants", app.requirePermission("comics:read", app.listVariantsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/comics/:id/variants", app.requirePermission("comics:write", app.createVariantHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/comics/:id/variants/:variant_id", app.requirePermission("comics:write", app.updateVariantHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/comics/:id/variants/:variant_id", app.requirePermission("comics:write", app.deleteVariantHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/comics/:id/prices", app.requirePermission("comics:read", app.listPriceHistoryHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/comics/:id/reviews", app.requirePermission("comics:read", app.listReviewsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/comics/:id/reviews", app.requireActivatedUser(app.createReviewHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/comics/:id/reviews/:review_id", app.requireActivatedUser(app.updateReviewHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/comics/:id/reviews/:review_id", app.requireActivatedUser(app.deleteReviewHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/comics/:id/inventory", app.requirePermission("inventory:write", app.listInventoryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/comics/:id/inventory", app.requirePermission("inventory:write", app.createInventoryHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/inventory/:id", app.requirePermission("inventory:write", app.showInventoryHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/inventory/:id", app.requirePermission("inventory:write", app.updateInventoryHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/inventory/:id", app.requirePermission("inventory:write", app.deleteInventoryHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/inventory/:id/adjustments", app.requirePermission("inventory:write", app.listAdjustmentsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/inventory/:id/adjustments", app.requirePermission("inventory:write", app.createAdjustmentHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/inventory/:id/reserve", app.requirePermission("inventory:write", app.reserveInventoryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/inventory/:id/release", app.requirePermission("inventory:write", app.releaseInventoryHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/imports/cbz", app.requirePermission("comics:write", app.importCBZHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/imports/shipping-list", app.requirePermission("inventory:write", app.importShippingListHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/creators", app.requirePermission("comics:write", app.createCreatorHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/creators", app.requirePermission("comics:read", app.listCreatorsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/creators/:id", app.requirePermission("comics:read", app.showCreatorHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/creators/:id", app.requirePermission("comics:write", app.updateCreatorHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/creators/:id", app.requirePermission("comics:write", app.deleteCreatorHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/series", app.requirePermission("comics:write", app.createSeriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/series", app.requirePermission("comics:read", app.listSeriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/series/:id", app.requirePermission("comics:read", app.showSeriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/series/:id/issues", app.requirePermission("comics:read", app.listSeriesIssuesHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/series/:id", app.requirePermission("comics:write", app.updateSeriesHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/series/:id", app.requirePermission("comics:write", app.deleteSeriesHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/publishers", app.requirePermission("publishers:write", app.createPublisherHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/publishers", app.requirePermission("comics:read", app.listPublishersHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/publishers/:id", app.requirePermission("comics:read", app.showPublisherHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/publishers/:id", app.requirePermission("publishers:write", app.updatePublisherHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/publishers/:id", app.requirePermission("publishers:write", app.deletePublisherHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/publishers/:id/imprints", app.requirePermission("publishers:write", app.createImprintHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/publishers/:id/imprints/:imprint_id", app.requirePermission("publishers:write", app.updateImprintHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/publishers/:id/imprints/:imprint_id", app.requirePermission("publishers:write", app.deleteImprintHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/releases", app.requirePermission("comics:read", app.listReleasesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/releases/calendar.ics", app.requireBasicAuth(app.requirePermission("comics:read", app.releasesCalendarHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/opds", app.requireBasicAuth(app.requirePermission("comics:read", app.opdsRootHandler)))
+	router.HandlerFunc(http.MethodGet, "/opds/all", app.requireBasicAuth(app.requirePermission("comics:read", app.opdsAllHandler)))
+	router.HandlerFunc(http.MethodGet, "/opds/search", app.requireBasicAuth(app.requirePermission("comics:read", app.opdsSearchHandler)))
+	router.HandlerFunc(http.MethodGet, "/opds/opensearch.xml", app.opdsOpenSearchHandler)
+
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/users/password", app.updateUserPasswordHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/users/email", app.confirmEmailChangeHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me", app.requireActivatedUser(app.updateCurrentUserHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/cart", app.requireActivatedUser(app.showCartHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/cart/items", app.requireActivatedUser(app.createCartItemHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/cart/items/:id", app.requireActivatedUser(app.updateCartItemHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/cart/items/:id", app.requireActivatedUser(app.deleteCartItemHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/checkout", app.requireActivatedUser(app.checkoutHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/orders", app.requireActivatedUser(app.listUserOrdersHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/orders/:id", app.requireActivatedUser(app.showUserOrderHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/orders/:id/cancel", app.requireActivatedUser(app.cancelUserOrderHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/pulllist", app.requireActivatedUser(app.listPullListHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/pulllist", app.requireActivatedUser(app.createPullListHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/pulllist/:id", app.requireActivatedUser(app.deletePullListHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/pulllist/holds", app.requireActivatedUser(app.listHoldsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/pulllist/holds/:id/release", app.requireActivatedUser(app.releaseHoldHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/preorders", app.requireActivatedUser(app.listUserPreordersHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/preorders", app.requireActivatedUser(app.createPreorderHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/preorders/:id", app.requireActivatedUser(app.updatePreorderHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/preorders/:id", app.requireActivatedUser(app.deletePreorderHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/preorders/summary", app.requirePermission("orders:manage", app.preorderSummaryHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/collection", app.requireActivatedUser(app.listCollectionHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/collection", app.requireActivatedUser(app.createCollectionItemHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/collection/items/:id", app.requireActivatedUser(app.showCollectionItemHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/collection/items/:id", app.requireActivatedUser(app.updateCollectionItemHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/collection/items/:id", app.requireActivatedUser(app.deleteCollectionItemHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/collection/stats", app.requireActivatedUser(app.collectionStatsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/collection/export", app.requireActivatedUser(app.exportCollectionHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/wishlist", app.requireActivatedUser(app.listWishlistHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/wishlist", app.requireActivatedUser(app.createWishlistHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/wishlist/:id", app.requireActivatedUser(app.deleteWishlistHandler))
 
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/read-states", app.requireActivatedUser(app.listReadStatesHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/read-states", app.requireActivatedUser(app.updateReadStatesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/continue-reading", app.requireActivatedUser(app.continueReadingHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/sessions", app.requireAuthenticatedUser(app.listSessionsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/sessions/:id", app.requireAuthenticatedUser(app.deleteSessionHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/api-keys", app.requireActivatedUser(app.listAPIKeysHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/api-keys", app.requireActivatedUser(app.createAPIKeyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/api-keys/:id", app.requireActivatedUser(app.deleteAPIKeyHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/orders", app.requirePermission("orders:manage", app.listOrdersHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/orders/:id", app.requirePermission("orders:manage", app.showOrderHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/orders/:id", app.requirePermission("orders:manage", app.updateOrderHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/payments", app.requireActivatedUser(app.createPaymentHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/payments", app.requireActivatedUser(app.listPaymentsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/payments/:id", app.requireActivatedUser(app.showPaymentHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/payments/:id/refund", app.requirePermission("orders:manage", app.refundPaymentHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/webhooks/payments", app.paymentWebhookHandler)
+
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/activation", app.createActivationTokenHandler)
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/tokens/authentication", app.requireAuthenticatedUser(app.deleteAuthenticationTokenHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/refresh", app.createRefreshTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/password-reset", app.createPasswordResetTokenHandler)
 
 	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
 
+	// Cover images are public, so serve them straight from the local storage directory.
+	router.ServeFiles("/media/covers/*filepath", http.Dir(filepath.Join(app.config.storage.dir, "covers")))
+
 	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router)))))
 }
@@ -21,6 +21,14 @@ func (app *application) serve() error {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
+	stopNotifiers := make(chan struct{})
+	app.background(func() {
+		app.notifyPreorders(stopNotifiers)
+	})
+	app.background(func() {
+		app.notifyWishlists(stopNotifiers)
+	})
+
 	shutdownError := make(chan error)
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -40,6 +48,7 @@ func (app *application) serve() error {
 		app.logger.PrintInfo("completing background tasks", map[string]string{
 			"addr": srv.Addr,
 		})
+		close(stopNotifiers)
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
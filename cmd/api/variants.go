@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+)
+
+// variantInput holds the client-supplied fields of a cover variant. It is shared by the
+// variant endpoints and createComicsHandler(), which accepts variants inline.
+type variantInput struct {
+	CoverLabel string     `json:"cover_label"`
+	Ratio      string     `json:"ratio"`
+	Artist     string     `json:"artist"`
+	SKU        string     `json:"sku"`
+	Price      data.Price `json:"price"`
+}
+
+func (in variantInput) toVariant() *data.Variant {
+	return &data.Variant{
+		CoverLabel: in.CoverLabel,
+		Ratio:      in.Ratio,
+		Artist:     in.Artist,
+		SKU:        in.SKU,
+		Price:      in.Price,
+	}
+}
+
+func (app *application) listVariantsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	comics, err := app.models.Comics.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"variants": comics.Variants}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createVariantHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	comics, err := app.models.Comics.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input variantInput
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	variant := input.toVariant()
+	variant.ComicsID = comics.ID
+
+	v := validator.New()
+	if data.ValidateVariant(v, variant); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Variants.Insert(variant)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSKU):
+			v.AddError("sku", "a variant with this sku already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/comics/%d/variants/%d", comics.ID, variant.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"variant": variant}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The readVariant() helper loads the variant named by the :variant_id URL parameter and
+// makes sure it belongs to the issue named by :id. It sends the appropriate error
+// response itself and returns nil if the variant can't be used.
+func (app *application) readVariant(w http.ResponseWriter, r *http.Request) *data.Variant {
+	comicsID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	variantID, err := app.readNamedIDParam(r, "variant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	variant, err := app.models.Variants.Get(variantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	if variant.ComicsID != comicsID {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+	return variant
+}
+
+func (app *application) updateVariantHandler(w http.ResponseWriter, r *http.Request) {
+	variant := app.readVariant(w, r)
+	if variant == nil {
+		return
+	}
+
+	var input struct {
+		CoverLabel *string     `json:"cover_label"`
+		Ratio      *string     `json:"ratio"`
+		Artist     *string     `json:"artist"`
+		SKU        *string     `json:"sku"`
+		Price      *data.Price `json:"price"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.CoverLabel != nil {
+		variant.CoverLabel = *input.CoverLabel
+	}
+	if input.Ratio != nil {
+		variant.Ratio = *input.Ratio
+	}
+	if input.Artist != nil {
+		variant.Artist = *input.Artist
+	}
+	if input.SKU != nil {
+		variant.SKU = *input.SKU
+	}
+	if input.Price != nil {
+		variant.Price = *input.Price
+	}
+
+	v := validator.New()
+	if data.ValidateVariant(v, variant); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Variants.Update(variant)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSKU):
+			v.AddError("sku", "a variant with this sku already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"variant": variant}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteVariantHandler(w http.ResponseWriter, r *http.Request) {
+	comicsID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	variantID, err := app.readNamedIDParam(r, "variant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Variants.Delete(comicsID, variantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "variant successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
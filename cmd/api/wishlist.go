@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"net/http"
+	"time"
+)
+
+func (app *application) listWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	items, err := app.models.Wishlist.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"wishlist": items}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The createWishlistHandler() adds an issue to the wishlist of the current user. The user
+// is emailed when the issue comes back in stock.
+func (app *application) createWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ComicsID int64 `json:"comics_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	comics, err := app.models.Comics.Get(input.ComicsID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("comics_id", "must reference an existing issue")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	item := &data.WishlistItem{
+		UserID:    app.contextGetUser(r).ID,
+		ComicsID:  comics.ID,
+		Title:     comics.Title,
+		Stock:     comics.Stock,
+		Available: comics.Available,
+	}
+
+	err = app.models.Wishlist.Insert(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateWishlistItem):
+			v.AddError("comics_id", "this issue is already on your wishlist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"item": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Wishlist.Delete(app.contextGetUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "issue successfully removed from wishlist"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The notifyWishlists() helper periodically looks for wished issues that came back in
+// stock and emails the users who wished for them, until stop is closed. Stock changes in
+// many places (receiving shipments, cancelled orders, manual adjustments), so comparing
+// the availability of every wished issue with the last run catches all of them.
+func (app *application) notifyWishlists(stop <-chan struct{}) {
+	ticker := time.NewTicker(app.config.wishlist.notifyInterval)
+	defer ticker.Stop()
+
+	for {
+		app.sendWishlistNotifications()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (app *application) sendWishlistNotifications() {
+	items, err := app.models.Wishlist.Restocked(app.config.wishlist.notifyCooldown)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+
+	for _, item := range items {
+		mailData := map[string]interface{}{
+			"name":  item.UserName,
+			"title": item.Title,
+		}
+		err = app.mailer.Send(item.UserEmail, "wishlist_available.tmpl", mailData)
+		if err == nil {
+			err = app.models.Wishlist.MarkNotified(item)
+		}
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"comics_id": fmt.Sprint(item.ComicsID),
+				"user_id":   fmt.Sprint(item.UserID),
+			})
+		}
+	}
+}
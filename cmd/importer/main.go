@@ -0,0 +1,125 @@
+// Command importer imports a distributor shipping list from a CSV or TSV file, the
+// same way as the POST /v1/imports/shipping-list endpoint:
+//
+//	importer -db-dsn=postgres://... -location=store -dry-run shipping.csv
+//
+// The report is written to standard output as JSON. The command exits with status 1 if
+// the list can't be imported, including when any of its rows is invalid. Unlike the API,
+// it doesn't email users whose pull lists include the new issues, although their holds
+// are still recorded.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/jsonlog"
+	"github.com/miras210/finalGolang/internal/shipping"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	var (
+		dsn      string
+		location string
+		dryRun   bool
+		userID   int64
+	)
+
+	flag.StringVar(&dsn, "db-dsn", os.Getenv("COMICS_STORE_DB_DSN"), "PostgreSQL DSN")
+	flag.StringVar(&location, "location", "store", "Inventory location receiving the shipment")
+	flag.BoolVar(&dryRun, "dry-run", false, "Report the changes without writing anything")
+	flag.Int64Var(&userID, "user-id", 0, "ID of the user recorded with the stock adjustments")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] shipping-list.csv\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	logger := jsonlog.New(os.Stderr, jsonlog.LevelInfo)
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	rows, err := shipping.Parse(bytes.NewReader(content))
+	if err != nil {
+		logger.PrintFatal(err, map[string]string{"file": path})
+	}
+
+	db, err := openDB(dsn)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+	defer db.Close()
+
+	hash := sha256.Sum256(content)
+	importer := &shipping.Importer{
+		Models:   data.NewModels(db),
+		Location: location,
+		Note:     "shipping list " + filepath.Base(path),
+		ListHash: hash[:],
+	}
+	if userID > 0 {
+		importer.UserID = &userID
+	}
+
+	report, err := importer.Plan(rows)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	if report.Valid() && !dryRun {
+		err = importer.Apply(report)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	if encErr := enc.Encode(report); encErr != nil && err == nil {
+		err = encErr
+	}
+
+	switch {
+	case err != nil:
+		logger.PrintFatal(err, nil)
+	case !report.Valid():
+		logger.PrintFatal(errors.New("shipping list has invalid rows"), map[string]string{
+			"invalid": fmt.Sprint(report.Invalid),
+		})
+	}
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
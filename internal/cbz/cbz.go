@@ -0,0 +1,203 @@
+package cbz
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrInvalidArchive = errors.New("invalid cbz archive")
+	ErrPageNotFound   = errors.New("page not found")
+	ErrPageTooLarge   = errors.New("page too large")
+)
+
+// MaxPageBytes is the largest uncompressed page image an archive may contain. Pages are
+// read into memory, so the limit keeps a small zip bomb from exhausting it.
+const MaxPageBytes = 50 << 20
+
+// imageExtensions lists the file extensions treated as pages of a comic archive.
+var imageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".webp": true,
+}
+
+// An Archive is an opened CBZ file, which is a zip archive of page images plus an
+// optional ComicInfo.xml metadata file.
+type Archive struct {
+	pages     []*zip.File
+	comicInfo *zip.File
+}
+
+// A Field is a single top-level element of a ComicInfo.xml document.
+type Field struct {
+	Name  string
+	Value string
+}
+
+func Open(r io.ReaderAt, size int64) (*Archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+
+	archive := &Archive{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || isHidden(f.Name) {
+			continue
+		}
+		if strings.EqualFold(path.Base(f.Name), "ComicInfo.xml") {
+			archive.comicInfo = f
+			continue
+		}
+		if imageExtensions[strings.ToLower(path.Ext(f.Name))] {
+			if f.UncompressedSize64 > MaxPageBytes {
+				return nil, fmt.Errorf("%w: %s is larger than %d MB", ErrInvalidArchive, f.Name, MaxPageBytes>>20)
+			}
+			archive.pages = append(archive.pages, f)
+		}
+	}
+
+	// Page order is given by the file names. Compare them naturally, so that "page2.jpg"
+	// comes before "page10.jpg" even when the numbers aren't zero-padded.
+	sort.SliceStable(archive.pages, func(i, j int) bool {
+		return naturalLess(archive.pages[i].Name, archive.pages[j].Name)
+	})
+
+	return archive, nil
+}
+
+// PageCount() returns the number of images in the archive.
+func (a *Archive) PageCount() int {
+	return len(a.pages)
+}
+
+// Page() opens the n-th page of the archive, counting from zero, and returns it together
+// with its file name. Reading the page stops after MaxPageBytes, even if the archive
+// understates its size.
+func (a *Archive) Page(n int) (io.ReadCloser, string, error) {
+	if n < 0 || n >= len(a.pages) {
+		return nil, "", ErrPageNotFound
+	}
+	if a.pages[n].UncompressedSize64 > MaxPageBytes {
+		return nil, "", ErrPageTooLarge
+	}
+	rc, err := a.pages[n].Open()
+	if err != nil {
+		return nil, "", err
+	}
+	return limitedReadCloser{io.LimitReader(rc, MaxPageBytes+1), rc}, a.pages[n].Name, nil
+}
+
+// limitedReadCloser reads through a limited reader and closes the underlying file.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// ReadPage() reads the whole n-th page of the archive into memory. Pages larger than
+// MaxPageBytes are refused with ErrPageTooLarge, whatever size the archive claims.
+func (a *Archive) ReadPage(n int) ([]byte, error) {
+	rc, _, err := a.Page(n)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, MaxPageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > MaxPageBytes {
+		return nil, ErrPageTooLarge
+	}
+	return content, nil
+}
+
+// ComicInfo() returns the top-level elements of the archive's ComicInfo.xml file in
+// document order, or nil if the archive doesn't contain one. Elements with nested
+// content (such as <Pages>) are returned with an empty value.
+func (a *Archive) ComicInfo() ([]Field, error) {
+	if a.comicInfo == nil {
+		return nil, nil
+	}
+
+	rc, err := a.comicInfo.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var doc struct {
+		XMLName xml.Name `xml:"ComicInfo"`
+		Fields  []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	}
+
+	err = xml.NewDecoder(rc).Decode(&doc)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+
+	fields := make([]Field, 0, len(doc.Fields))
+	for _, f := range doc.Fields {
+		fields = append(fields, Field{Name: f.XMLName.Local, Value: strings.TrimSpace(f.Value)})
+	}
+	return fields, nil
+}
+
+// isHidden() reports whether a file is metadata added by an operating system, such as
+// the __MACOSX folder or ._ resource forks, rather than real archive content.
+func isHidden(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+// naturalLess() compares two strings treating runs of digits as numbers.
+func naturalLess(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		ra, rb := rune(a[0]), rune(b[0])
+		if unicode.IsDigit(ra) && unicode.IsDigit(rb) {
+			na, restA := splitDigits(a)
+			nb, restB := splitDigits(b)
+			na, nb = strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = restA, restB
+			continue
+		}
+		if ra != rb {
+			return ra < rb
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i], s[i:]
+}
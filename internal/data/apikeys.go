@@ -0,0 +1,238 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/miras210/finalGolang/internal/validator"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, so that the authenticate middleware can tell API
+// keys from authentication tokens sent in the same Authorization header.
+const APIKeyPrefix = "csk_"
+
+// An APIKey lets scripts act for a user without their password. A key never expires,
+// and only carries the permissions it was created with, as long as the user still has
+// them. The plaintext key is only known when the key is created.
+type APIKey struct {
+	ID          int64       `json:"id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UserID      int64       `json:"-"`
+	Name        string      `json:"name"`
+	Plaintext   string      `json:"key,omitempty"`
+	Hash        []byte      `json:"-"`
+	Permissions Permissions `json:"permissions"`
+	LastUsedAt  *time.Time  `json:"last_used_at,omitempty"`
+}
+
+// generateAPIKey() creates an API key with a random plaintext made of the prefix and 32
+// base-32 characters.
+func generateAPIKey(userID int64, name string, permissions Permissions) (*APIKey, error) {
+	randomBytes := make([]byte, 20)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+	key := &APIKey{
+		UserID:      userID,
+		Name:        name,
+		Permissions: permissions,
+	}
+	key.Plaintext = APIKeyPrefix + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	hash := sha256.Sum256([]byte(key.Plaintext))
+	key.Hash = hash[:]
+	return key, nil
+}
+
+// Check that the plaintext API key has the prefix and is exactly 36 bytes long.
+func ValidateAPIKeyPlaintext(v *validator.Validator, keyPlaintext string) {
+	v.Check(strings.HasPrefix(keyPlaintext, APIKeyPrefix), "key", "must be an API key")
+	v.Check(len(keyPlaintext) == len(APIKeyPrefix)+32, "key", "must be 36 bytes long")
+}
+
+// ValidateAPIKey() checks the name of an API key and that its permissions are a subset
+// of the permissions of its user.
+func ValidateAPIKey(v *validator.Validator, key *APIKey, userPermissions Permissions) {
+	v.Check(key.Name != "", "name", "must be provided")
+	v.Check(len(key.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(len(key.Permissions) > 0, "permissions", "must contain at least 1 permission")
+	v.Check(validator.Unique(key.Permissions), "permissions", "must not contain duplicate values")
+	for _, code := range key.Permissions {
+		if !userPermissions.Include(code) {
+			v.AddError("permissions", "must only contain permissions of your user account")
+			break
+		}
+	}
+}
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+// The New() method is a shortcut which creates a new APIKey struct and then inserts the
+// data in the api_keys table.
+func (m APIKeyModel) New(userID int64, name string, permissions Permissions) (*APIKey, error) {
+	key, err := generateAPIKey(userID, name, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO api_keys (user_id, name, hash, permissions)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{key.UserID, key.Name, key.Hash, pq.Array(key.Permissions)}
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetAllForUser() lists the API keys of a user, the most recently created first.
+func (m APIKeyModel) GetAllForUser(userID int64) ([]*APIKey, error) {
+	query := `
+		SELECT id, created_at, user_id, name, permissions, last_used_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		var key APIKey
+		err := rows.Scan(
+			&key.ID,
+			&key.CreatedAt,
+			&key.UserID,
+			&key.Name,
+			pq.Array(&key.Permissions),
+			&key.LastUsedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetForPlaintext() looks up an API key by its plaintext and returns it with the user it
+// belongs to.
+func (m APIKeyModel) GetForPlaintext(keyPlaintext string) (*APIKey, *User, error) {
+	keyHash := sha256.Sum256([]byte(keyPlaintext))
+	query := `
+		SELECT api_keys.id, api_keys.created_at, api_keys.name, api_keys.permissions, api_keys.last_used_at,
+		       users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+		FROM api_keys
+		INNER JOIN users ON users.id = api_keys.user_id
+		WHERE api_keys.hash = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var (
+		key  APIKey
+		user User
+	)
+	err := m.DB.QueryRowContext(ctx, query, keyHash[:]).Scan(
+		&key.ID,
+		&key.CreatedAt,
+		&key.Name,
+		pq.Array(&key.Permissions),
+		&key.LastUsedAt,
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+	key.UserID = user.ID
+	key.Hash = keyHash[:]
+	return &key, &user, nil
+}
+
+// Touch() records that an API key was just used, at most once a minute.
+func (m APIKeyModel) Touch(id int64) error {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1
+		AND (last_used_at IS NULL OR last_used_at < NOW() - interval '1 minute')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (m APIKeyModel) Delete(userID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `
+		DELETE FROM api_keys
+		WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteAllForUser() revokes every API key of a user.
+func (m APIKeyModel) DeleteAllForUser(userID int64) error {
+	query := `
+		DELETE FROM api_keys
+		WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
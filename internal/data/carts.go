@@ -0,0 +1,201 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrCartQuantity is returned when adding to a cart item would take it over the maximum
+// quantity for a single item.
+var ErrCartQuantity = errors.New("cart quantity out of range")
+
+// MaxCartQuantity is the most copies of a single item a cart can hold.
+const MaxCartQuantity = 100
+
+// A CartItem is a line in a user's shopping cart. The title and unit price are read from
+// the issue (or variant) when the cart is loaded, so they always reflect the catalogue.
+type CartItem struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	UserID    int64     `json:"-"`
+	ComicsID  int64     `json:"comics_id"`
+	VariantID *int64    `json:"variant_id,omitempty"`
+	Title     string    `json:"title"`
+	UnitPrice Price     `json:"unit_price"`
+	Quantity  int32     `json:"quantity"`
+	Version   int32     `json:"version"`
+}
+
+type Cart struct {
+	Items []*CartItem `json:"items"`
+	Total Price       `json:"total"`
+}
+
+type CartModel struct {
+	DB *sql.DB
+}
+
+// cartItemsQuery selects the items of a user's cart together with their title and
+// current price. A variant's price takes precedence over the price of its issue.
+const cartItemsQuery = `
+		SELECT cart_items.id, cart_items.created_at, cart_items.user_id, cart_items.comic_id, cart_items.variant_id,
+		       comics.title || COALESCE(' (' || comic_variants.cover_label || ')', ''),
+		       COALESCE(comic_variants.price, comics.price), cart_items.quantity, cart_items.version
+		FROM cart_items
+		INNER JOIN comics ON comics.id = cart_items.comic_id
+		LEFT JOIN comic_variants ON comic_variants.id = cart_items.variant_id
+		WHERE cart_items.user_id = $1`
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// The getCartItems() helper runs cartItemsQuery, plus the given suffix, using either the
+// database connection pool or a transaction. The first argument must be the user id.
+func getCartItems(ctx context.Context, q queryer, suffix string, args ...interface{}) ([]*CartItem, error) {
+	rows, err := q.QueryContext(ctx, cartItemsQuery+suffix, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*CartItem{}
+	for rows.Next() {
+		var item CartItem
+		err := rows.Scan(
+			&item.ID,
+			&item.CreatedAt,
+			&item.UserID,
+			&item.ComicsID,
+			&item.VariantID,
+			&item.Title,
+			&item.UnitPrice,
+			&item.Quantity,
+			&item.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Get() returns the cart of a user. A user who hasn't added anything yet gets an empty
+// cart.
+func (m CartModel) Get(userID int64) (*Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	items, err := getCartItems(ctx, m.DB, ` ORDER BY cart_items.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart := &Cart{Items: items}
+	for _, item := range items {
+		cart.Total += item.UnitPrice * Price(item.Quantity)
+	}
+	return cart, nil
+}
+
+// AddItem() puts an item into a user's cart. If the cart already holds the same issue
+// and variant, the quantities are added together instead.
+func (m CartModel) AddItem(item *CartItem) error {
+	query := `
+		INSERT INTO cart_items (user_id, comic_id, variant_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, comic_id, COALESCE(variant_id, 0))
+		DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, version = cart_items.version + 1
+		RETURNING id, created_at, quantity, version`
+
+	args := []interface{}{item.UserID, item.ComicsID, item.VariantID, item.Quantity}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&item.ID, &item.CreatedAt, &item.Quantity, &item.Version)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `violates check constraint "cart_items_quantity_check"`):
+			return ErrCartQuantity
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetItem() fetches a single item from a user's cart. Items in other users' carts are
+// reported as not found.
+func (m CartModel) GetItem(userID, id int64) (*CartItem, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	items, err := getCartItems(ctx, m.DB, ` AND cart_items.id = $2`, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrRecordNotFound
+	}
+	return items[0], nil
+}
+
+func (m CartModel) UpdateItem(item *CartItem) error {
+	query := `UPDATE cart_items
+			SET quantity = $1, version = version + 1
+			WHERE id = $2 AND user_id = $3 AND version = $4
+			RETURNING version`
+	args := []interface{}{item.Quantity, item.ID, item.UserID, item.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&item.Version)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `violates check constraint "cart_items_quantity_check"`):
+			return ErrCartQuantity
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m CartModel) DeleteItem(userID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM cart_items
+			WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
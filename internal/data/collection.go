@@ -0,0 +1,327 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/validator"
+	"time"
+)
+
+// A CollectionItem is a physical copy of an issue in the personal collection of a user.
+// The title, issue number and publisher are read from the issue whenever an item is
+// loaded.
+type CollectionItem struct {
+	ID            int64       `json:"id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UserID        int64       `json:"-"`
+	ComicsID      int64       `json:"comics_id"`
+	Title         string      `json:"title"`
+	IssueNumber   IssueNumber `json:"issue_number,omitempty"`
+	Publisher     string      `json:"publisher,omitempty"`
+	Grade         Grade       `json:"grade"`
+	GradeValue    float64     `json:"grade_value"`
+	PurchasePrice Price       `json:"purchase_price"`
+	PurchaseDate  *Date       `json:"purchase_date,omitempty"`
+	Notes         string      `json:"notes,omitempty"`
+	Version       int32       `json:"version"`
+}
+
+// CollectionStats sums up the collection of a user, overall and per publisher. The
+// estimated value is what the items would cost at the latest recorded price of their
+// issues.
+type CollectionStats struct {
+	Count          int               `json:"count"`
+	TotalSpent     Price             `json:"total_spent"`
+	EstimatedValue Price             `json:"estimated_value"`
+	Publishers     []*PublisherStats `json:"by_publisher"`
+}
+
+// PublisherStats sums up the items of a collection from a single publisher. Items of
+// issues without a publisher are counted with a nil PublisherID.
+type PublisherStats struct {
+	PublisherID    *int64 `json:"publisher_id"`
+	Publisher      string `json:"publisher"`
+	Count          int    `json:"count"`
+	TotalSpent     Price  `json:"total_spent"`
+	EstimatedValue Price  `json:"estimated_value"`
+}
+
+func ValidateCollectionItem(v *validator.Validator, item *CollectionItem) {
+	v.Check(item.Grade != "", "grade", "must be provided")
+	if item.Grade != "" {
+		_, ok := item.Grade.Numeric()
+		v.Check(ok, "grade", "must be a grade from 0.5 to 10.0 on the CGC scale, or a raw grade such as VF/NM")
+	}
+
+	if item.PurchaseDate != nil {
+		v.Check(!item.PurchaseDate.After(Today().Time), "purchase_date", "must not be in the future")
+	}
+
+	v.Check(len(item.Notes) <= 1000, "notes", "must not be more than 1000 bytes long")
+}
+
+type CollectionModel struct {
+	DB *sql.DB
+}
+
+// Insert() adds an item to a collection. The grade must be valid; its value on the
+// 10-point scale is stored next to it, so that items can be sorted by condition.
+func (m CollectionModel) Insert(item *CollectionItem) error {
+	item.GradeValue, _ = item.Grade.Numeric()
+
+	query := `
+		INSERT INTO collection_items (user_id, comic_id, grade, grade_value, purchase_price, purchase_date, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{
+		item.UserID,
+		item.ComicsID,
+		item.Grade,
+		item.GradeValue,
+		item.PurchasePrice,
+		item.PurchaseDate,
+		item.Notes,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&item.ID, &item.CreatedAt, &item.Version)
+}
+
+// collectionQuery selects the items of the collection of user $1 together with the title,
+// issue number and publisher of their issue. GetAll() wraps it in a subquery, so that
+// the result can be sorted by its column names without ambiguity.
+const collectionQuery = `
+		SELECT collection_items.id, collection_items.created_at, collection_items.user_id,
+		       collection_items.comic_id, comics.title, comics.issue_number,
+		       COALESCE(publishers.name, '') AS publisher, collection_items.grade,
+		       collection_items.grade_value, collection_items.purchase_price,
+		       collection_items.purchase_date, collection_items.notes, collection_items.version
+		FROM collection_items
+		INNER JOIN comics ON comics.id = collection_items.comic_id
+		LEFT JOIN publishers ON publishers.id = comics.publisher_id
+		WHERE collection_items.user_id = $1`
+
+// The scanCollectionItem() helper scans a row of collectionQuery, after any columns the
+// caller selects in front of it.
+func scanCollectionItem(row interface{ Scan(...interface{}) error }, dest ...interface{}) (*CollectionItem, error) {
+	var item CollectionItem
+	dest = append(dest,
+		&item.ID,
+		&item.CreatedAt,
+		&item.UserID,
+		&item.ComicsID,
+		&item.Title,
+		&item.IssueNumber,
+		&item.Publisher,
+		&item.Grade,
+		&item.GradeValue,
+		&item.PurchasePrice,
+		&item.PurchaseDate,
+		&item.Notes,
+		&item.Version,
+	)
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Get() fetches an item of the collection of a user. Items of other users are reported
+// as not found.
+func (m CollectionModel) Get(userID, id int64) (*CollectionItem, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	item, err := scanCollectionItem(m.DB.QueryRowContext(ctx, collectionQuery+` AND collection_items.id = $2`, userID, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return item, nil
+}
+
+// GetAll() lists a page of the collection of a user. A publisherID of -1 lists the items
+// of every publisher.
+func (m CollectionModel) GetAll(userID, publisherID int64, filters Filters) ([]*CollectionItem, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), items.*
+		FROM (%s AND (comics.publisher_id = $2 OR $2 = -1)) AS items
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, collectionQuery, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, publisherID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	items := []*CollectionItem{}
+	for rows.Next() {
+		item, err := scanCollectionItem(rows, &totalRecords)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return items, metadata, nil
+}
+
+// GetAllForExport() returns the whole collection of a user, ordered by title.
+func (m CollectionModel) GetAllForExport(userID int64) ([]*CollectionItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, collectionQuery+` ORDER BY comics.title, collection_items.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*CollectionItem{}
+	for rows.Next() {
+		item, err := scanCollectionItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (m CollectionModel) Update(item *CollectionItem) error {
+	item.GradeValue, _ = item.Grade.Numeric()
+
+	query := `
+		UPDATE collection_items
+		SET grade = $1, grade_value = $2, purchase_price = $3, purchase_date = $4, notes = $5, version = version + 1
+		WHERE id = $6 AND version = $7 AND user_id = $8
+		RETURNING version`
+
+	args := []interface{}{
+		item.Grade,
+		item.GradeValue,
+		item.PurchasePrice,
+		item.PurchaseDate,
+		item.Notes,
+		item.ID,
+		item.Version,
+		item.UserID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&item.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m CollectionModel) Delete(userID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM collection_items
+			WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetStats() sums up the collection of a user. Issues without a price history yet are
+// valued at their current price. Publishers are listed by the number of items, most
+// first.
+func (m CollectionModel) GetStats(userID int64) (*CollectionStats, error) {
+	query := `
+		SELECT comics.publisher_id, COALESCE(publishers.name, ''), count(*), sum(collection_items.purchase_price),
+		       sum(COALESCE(latest.price, comics.price))
+		FROM collection_items
+		INNER JOIN comics ON comics.id = collection_items.comic_id
+		LEFT JOIN publishers ON publishers.id = comics.publisher_id
+		LEFT JOIN LATERAL (
+			SELECT price FROM comic_prices
+			WHERE comic_prices.comic_id = comics.id AND comic_prices.variant_id IS NULL
+			AND comic_prices.effective_date <= CURRENT_DATE
+			ORDER BY comic_prices.effective_date DESC
+			LIMIT 1) AS latest ON true
+		WHERE collection_items.user_id = $1
+		GROUP BY comics.publisher_id, publishers.name
+		ORDER BY count(*) DESC, publishers.name NULLS LAST`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &CollectionStats{Publishers: []*PublisherStats{}}
+	for rows.Next() {
+		var publisher PublisherStats
+		err := rows.Scan(
+			&publisher.PublisherID,
+			&publisher.Publisher,
+			&publisher.Count,
+			&publisher.TotalSpent,
+			&publisher.EstimatedValue,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stats.Count += publisher.Count
+		stats.TotalSpent += publisher.TotalSpent
+		stats.EstimatedValue += publisher.EstimatedValue
+		stats.Publishers = append(stats.Publishers, &publisher)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -5,58 +5,223 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"github.com/miras210/finalGolang/internal/validator"
+	"regexp"
+	"strings"
 	"time"
 )
 
+var (
+	ErrDuplicateItemCode = errors.New("duplicate item code")
+	ErrDuplicateUPC      = errors.New("duplicate upc")
+)
+
+// upcRX matches a UPC-A barcode, optionally followed by the 5 digit add-on that comic
+// publishers use for the issue number and cover.
+var upcRX = regexp.MustCompile(`^\d{12}(\d{5})?$`)
+
 type Comics struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"-"`
-	Title     string    `json:"title"`
-	Year      int32     `json:"year,omitempty"`
-	Pages     Pages     `json:"pages,omitempty"`
-	Version   int32     `json:"version"`
+	ID          int64       `json:"id"`
+	CreatedAt   time.Time   `json:"-"`
+	Title       string      `json:"title"`
+	Summary     string      `json:"summary,omitempty"`
+	Year        int32       `json:"year,omitempty"`
+	ReleaseDate *Date       `json:"release_date,omitempty"`
+	OnSaleDate  *Date       `json:"on_sale_date,omitempty"`
+	FOCDate     *Date       `json:"foc_date,omitempty"`
+	Pages       Pages       `json:"pages,omitempty"`
+	SeriesID    *int64      `json:"series_id,omitempty"`
+	IssueNumber IssueNumber `json:"issue_number,omitempty"`
+	PublisherID *int64      `json:"publisher_id,omitempty"`
+	ImprintID   *int64      `json:"imprint_id,omitempty"`
+	ItemCode    string      `json:"item_code,omitempty"`
+	UPC         string      `json:"upc,omitempty"`
+	Credits     []Credit    `json:"credits,omitempty"`
+	Variants    []*Variant  `json:"variants,omitempty"`
+	CoverURLs   CoverURLs   `json:"cover_urls,omitempty"`
+	ArchiveKey  string      `json:"-"`
+	Price       Price       `json:"price"`
+	Stock       int32       `json:"stock"`
+	Available   bool        `json:"available"`
+	Rating      float64     `json:"rating"`
+	ReviewCount int32       `json:"review_count"`
+	Version     int32       `json:"version"`
 }
 
 func ValidateComics(v *validator.Validator, comics *Comics) {
 	v.Check(comics.Title != "", "title", "must be provided")
 	v.Check(len(comics.Title) <= 500, "title", "must not be more than 500 bytes long")
 
+	v.Check(len(comics.Summary) <= 10_000, "summary", "must not be more than 10000 bytes long")
+
 	v.Check(comics.Year != 0, "year", "must be provided")
 	v.Check(comics.Year >= 1888, "year", "must be greater than 1888")
-	v.Check(comics.Year <= int32(time.Now().Year()), "year", "must not be in the future")
+	v.Check(comics.Year <= int32(time.Now().Year())+1, "year", "must not be more than a year in the future")
+
+	if comics.ReleaseDate != nil {
+		v.Check(int32(comics.ReleaseDate.Year()) == comics.Year, "release_date", "must be in the same year as the issue")
+	}
+	if comics.FOCDate != nil {
+		v.Check(comics.OnSaleDate != nil, "on_sale_date", "must be provided when foc_date is set")
+		v.Check(comics.OnSaleDate == nil || comics.FOCDate.Before(comics.OnSaleDate.Time), "foc_date", "must be before the on-sale date")
+	}
 
 	v.Check(comics.Pages != 0, "pages", "must be provided")
 	v.Check(comics.Pages > 0, "pages", "must be a positive integer")
 
+	if comics.SeriesID != nil {
+		v.Check(*comics.SeriesID > 0, "series_id", "must be a positive integer")
+		v.Check(comics.IssueNumber != "", "issue_number", "must be provided when series_id is set")
+	}
+	v.Check(len(comics.IssueNumber) <= 50, "issue_number", "must not be more than 50 bytes long")
+
+	v.Check(comics.Price >= 0, "price", "must not be negative")
+
+	v.Check(len(comics.ItemCode) <= 50, "item_code", "must not be more than 50 bytes long")
+	if comics.UPC != "" {
+		v.Check(validator.Matches(comics.UPC, upcRX), "upc", "must be 12 digits, optionally followed by a 5 digit add-on")
+	}
+
+	if comics.PublisherID != nil {
+		v.Check(*comics.PublisherID > 0, "publisher_id", "must be a positive integer")
+	}
+	if comics.ImprintID != nil {
+		v.Check(*comics.ImprintID > 0, "imprint_id", "must be a positive integer")
+		v.Check(comics.PublisherID != nil, "publisher_id", "must be provided when imprint_id is set")
+	}
+
+	ValidateCredits(v, comics.Credits)
+
+	skus := make([]string, 0, len(comics.Variants))
+	for i, variant := range comics.Variants {
+		validateVariant(v, variant, fmt.Sprintf("variants[%d].", i))
+		skus = append(skus, variant.SKU)
+	}
+	v.Check(validator.Unique(skus), "variants", "must not contain duplicate skus")
 }
 
 type ComicsModel struct {
 	DB *sql.DB
 }
 
-// Add a placeholder method for inserting a new record in the movies table.
+// The comicsForeignKeyError() helper translates a foreign key violation on one of the
+// comics reference columns into the matching ErrUnknown* error, and a duplicate item
+// code or UPC into ErrDuplicateItemCode or ErrDuplicateUPC.
+func comicsForeignKeyError(err error) error {
+	switch {
+	case strings.Contains(err.Error(), `violates foreign key constraint "comics_series_id_fkey"`):
+		return ErrUnknownSeries
+	case strings.Contains(err.Error(), `violates foreign key constraint "comics_publisher_id_fkey"`):
+		return ErrUnknownPublisher
+	case strings.Contains(err.Error(), `violates foreign key constraint "comics_imprint_id_fkey"`):
+		return ErrUnknownImprint
+	case err.Error() == `pq: duplicate key value violates unique constraint "comics_item_code_idx"`:
+		return ErrDuplicateItemCode
+	case err.Error() == `pq: duplicate key value violates unique constraint "comics_upc_idx"`:
+		return ErrDuplicateUPC
+	default:
+		return err
+	}
+}
+
+// Insert() adds a new issue together with any variants and credits it was created with.
+// All of them happen in a single transaction, so a duplicate variant SKU or an unknown
+// creator doesn't leave a half-created issue behind. An issue of a series is also held
+// for every user with the series on their pull list, and the prices of the issue and its
+// variants start their price history.
 func (m ComicsModel) Insert(comics *Comics) error {
-	query := `INSERT INTO comics (title, year, pages)
-			VALUES ($1, $2, $3)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertComics(ctx, tx, comics)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertComics(ctx context.Context, tx *sql.Tx, comics *Comics) error {
+	query := `INSERT INTO comics (title, year, pages, series_id, issue_number, issue_sort, publisher_id, imprint_id, summary, price,
+			    release_date, on_sale_date, foc_date, item_code, upc)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 			RETURNING id, created_at, version`
 
-	args := []interface{}{comics.Title, comics.Year, comics.Pages}
+	args := []interface{}{
+		comics.Title,
+		comics.Year,
+		comics.Pages,
+		comics.SeriesID,
+		comics.IssueNumber,
+		comics.IssueNumber.SortKey(),
+		comics.PublisherID,
+		comics.ImprintID,
+		comics.Summary,
+		comics.Price,
+		comics.ReleaseDate,
+		comics.OnSaleDate,
+		comics.FOCDate,
+		comics.ItemCode,
+		comics.UPC,
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&comics.ID, &comics.CreatedAt, &comics.Version)
+	if err != nil {
+		return comicsForeignKeyError(err)
+	}
+
+	err = recordPrice(ctx, tx, comics.ID, nil, comics.Price)
+	if err != nil {
+		return err
+	}
+
+	for _, variant := range comics.Variants {
+		variant.ComicsID = comics.ID
+		err = insertVariant(ctx, tx, variant)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(comics.Credits) > 0 {
+		err = setCredits(ctx, tx, comics.ID, comics.Credits)
+		if err != nil {
+			return err
+		}
+	}
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&comics.ID, &comics.CreatedAt, &comics.Version)
+	return recordHolds(ctx, tx, comics)
 }
 
-// Add a placeholder method for fetching a specific record from the movies table.
+// comicsStockColumn computes the stock of an issue: the number of unreserved copies of
+// the issue and its variants over all inventory locations. An issue is available while
+// its stock is above zero.
+const comicsStockColumn = `(SELECT COALESCE(sum(on_hand - reserved), 0) FROM inventory WHERE inventory.comic_id = comics.id)`
+
+// comicsRatingColumn computes the average star rating of an issue, rounded to two decimal
+// places. Issues without reviews have a rating of 0. comicsReviewCountColumn counts the
+// reviews of the issue.
+const (
+	comicsRatingColumn      = `(SELECT COALESCE(round(avg(rating), 2), 0) FROM reviews WHERE reviews.comic_id = comics.id)`
+	comicsReviewCountColumn = `(SELECT count(*) FROM reviews WHERE reviews.comic_id = comics.id)`
+)
+
+// Get() fetches a specific issue along with all of its cover variants.
 func (m ComicsModel) Get(id int64) (*Comics, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
-	query := `SELECT id, created_at, title, year, pages, version
+	query := fmt.Sprintf(`SELECT id, created_at, title, summary, year, pages, series_id, issue_number, publisher_id, imprint_id,
+			       cover_urls, archive_key, price, release_date, on_sale_date, foc_date, item_code, upc, %s, %s, %s, version
 			FROM comics
-			WHERE id = $1`
+			WHERE id = $1`, comicsStockColumn, comicsRatingColumn, comicsReviewCountColumn)
 
 	var comics Comics
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -67,8 +232,24 @@ func (m ComicsModel) Get(id int64) (*Comics, error) {
 		&comics.ID,
 		&comics.CreatedAt,
 		&comics.Title,
+		&comics.Summary,
 		&comics.Year,
 		&comics.Pages,
+		&comics.SeriesID,
+		&comics.IssueNumber,
+		&comics.PublisherID,
+		&comics.ImprintID,
+		&comics.CoverURLs,
+		&comics.ArchiveKey,
+		&comics.Price,
+		&comics.ReleaseDate,
+		&comics.OnSaleDate,
+		&comics.FOCDate,
+		&comics.ItemCode,
+		&comics.UPC,
+		&comics.Stock,
+		&comics.Rating,
+		&comics.ReviewCount,
 		&comics.Version,
 	)
 	if err != nil {
@@ -79,39 +260,212 @@ func (m ComicsModel) Get(id int64) (*Comics, error) {
 			return nil, err
 		}
 	}
+	comics.Available = comics.Stock > 0
+
+	comics.Variants, err = VariantModel{DB: m.DB}.GetAllForComics(comics.ID)
+	if err != nil {
+		return nil, err
+	}
 	return &comics, nil
 }
 
-// Add a placeholder method for updating a specific record in the movies table.
+// GetBySeriesIssue() looks up an issue by its series and issue number. Issue numbers are
+// compared by their sort key, so "1" and "01" are considered the same issue.
+func (m ComicsModel) GetBySeriesIssue(seriesID int64, issueNumber IssueNumber) (*Comics, error) {
+	query := `SELECT id
+			FROM comics
+			WHERE series_id = $1 AND issue_sort = $2
+			ORDER BY id
+			LIMIT 1`
+
+	var id int64
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, seriesID, issueNumber.SortKey()).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return m.Get(id)
+}
+
+// GetPageCounts() returns the page count of each of the given issues that exists,
+// keyed by issue id.
+func (m ComicsModel) GetPageCounts(ids []int64) (map[int64]Pages, error) {
+	query := `SELECT id, pages
+			FROM comics
+			WHERE id = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pages := make(map[int64]Pages)
+	for rows.Next() {
+		var id int64
+		var count Pages
+		err := rows.Scan(&id, &count)
+		if err != nil {
+			return nil, err
+		}
+		pages[id] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// GetByItemCode() looks up an issue by its distributor item code or, failing that, by
+// its UPC. Empty values never match.
+func (m ComicsModel) GetByItemCode(itemCode, upc string) (*Comics, error) {
+	query := `SELECT id
+			FROM comics
+			WHERE (item_code = $1 AND $1 <> '') OR (upc = $2 AND $2 <> '')
+			ORDER BY item_code = $1 DESC, id
+			LIMIT 1`
+
+	var id int64
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, itemCode, upc).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return m.Get(id)
+}
+
+// Update() saves the changes to an issue. Its credits are replaced in the same
+// transaction when Credits isn't nil; Get() leaves Credits nil, so issues loaded with it
+// keep their credits.
 func (m ComicsModel) Update(comics *Comics) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = updateComics(ctx, tx, comics)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func updateComics(ctx context.Context, tx *sql.Tx, comics *Comics) error {
 	query := `UPDATE comics
-			SET title = $1, year = $2, pages = $3, version = version + 1
-			WHERE id = $4 AND version = $5
+			SET title = $1, year = $2, pages = $3, series_id = $4, issue_number = $5, issue_sort = $6,
+			    publisher_id = $7, imprint_id = $8, cover_urls = $9, summary = $10, archive_key = $11,
+			    price = $12, release_date = $13, on_sale_date = $14, foc_date = $15,
+			    item_code = $16, upc = $17, version = version + 1
+			WHERE id = $18 AND version = $19
 			RETURNING version`
 	args := []interface{}{
 		comics.Title,
 		comics.Year,
 		comics.Pages,
+		comics.SeriesID,
+		comics.IssueNumber,
+		comics.IssueNumber.SortKey(),
+		comics.PublisherID,
+		comics.ImprintID,
+		comics.CoverURLs,
+		comics.Summary,
+		comics.ArchiveKey,
+		comics.Price,
+		comics.ReleaseDate,
+		comics.OnSaleDate,
+		comics.FOCDate,
+		comics.ItemCode,
+		comics.UPC,
 		comics.ID,
 		comics.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&comics.Version)
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&comics.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
 		default:
-			return err
+			return comicsForeignKeyError(err)
 		}
 	}
+
+	err = recordPrice(ctx, tx, comics.ID, nil, comics.Price)
+	if err != nil {
+		return err
+	}
+
+	if comics.Credits != nil {
+		return setCredits(ctx, tx, comics.ID, comics.Credits)
+	}
 	return nil
 }
 
-// Add a placeholder method for deleting a specific record from the movies table.
+// Import() inserts an issue without an ID, or updates an existing one, and then calls
+// attach with the saved issue so that files named after its ID can be stored. The
+// archive key and cover URLs set by attach are saved before the transaction commits, so
+// a failure anywhere leaves the issue as it was. Storing and resizing files can take a
+// while, hence the longer timeout.
+func (m ComicsModel) Import(comics *Comics, attach func(*Comics) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if comics.ID == 0 {
+		err = insertComics(ctx, tx, comics)
+	} else {
+		err = updateComics(ctx, tx, comics)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = attach(comics)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE comics
+		SET archive_key = $1, cover_urls = $2
+		WHERE id = $3`
+	_, err = tx.ExecContext(ctx, query, comics.ArchiveKey, comics.CoverURLs, comics.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete() removes an issue. Its variants and credits are removed along with it by the
+// ON DELETE CASCADE rules on their tables.
 func (m ComicsModel) Delete(id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
@@ -136,20 +490,66 @@ func (m ComicsModel) Delete(id int64) error {
 	return nil
 }
 
-func (m ComicsModel) GetAll(title string, year int, filters Filters) ([]*Comics, Metadata, error) {
+// ComicsFilter holds the conditions GetAll() and GetPublisherFacets() select issues by.
+// A zero value leaves its condition out, so callers only set the ones they need. An issue
+// is in stock when any of its inventory items has unreserved copies, and read when the
+// user given by ReaderID has finished it.
+type ComicsFilter struct {
+	Title       string
+	Year        int
+	CreatorID   int
+	SeriesID    int
+	PublisherID int
+	InStock     *bool
+	Read        *bool
+	ReaderID    int64
+}
+
+// args() returns the query arguments comicsWhereClause expects, in order.
+func (f ComicsFilter) args() []interface{} {
+	return []interface{}{f.Title, f.Year, f.CreatorID, f.SeriesID, f.PublisherID, f.InStock, f.Read, f.ReaderID}
+}
+
+// comicsWhereClause holds the filtering conditions shared by GetAll() and
+// GetPublisherFacets(). It takes the arguments returned by ComicsFilter.args() as the
+// first eight query arguments.
+const comicsWhereClause = `
+		WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
+		AND (year = $2 OR $2 = 0)
+		AND (id IN (SELECT comic_id FROM comics_creators WHERE creator_id = $3) OR $3 = 0)
+		AND (series_id = $4 OR $4 = 0)
+		AND (publisher_id = $5 OR $5 = 0)
+		AND ($6::boolean IS NULL
+			OR EXISTS (SELECT 1 FROM inventory WHERE inventory.comic_id = comics.id AND on_hand > reserved) = $6::boolean)
+		AND ($7::boolean IS NULL
+			OR EXISTS (SELECT 1 FROM read_states WHERE read_states.comic_id = comics.id AND read_states.user_id = $8
+				AND read_states.status = 'read') = $7::boolean)`
+
+// Note that sorting by "issue_number" orders by the issue_sort column instead, which
+// holds the natural sort key of the issue number, and that sorting by "rating" orders by
+// the average star rating.
+func (m ComicsModel) GetAll(filter ComicsFilter, filters Filters) ([]*Comics, Metadata, error) {
+	sortColumn := filters.sortColumn()
+	switch sortColumn {
+	case "issue_number":
+		sortColumn = "issue_sort"
+	case "rating":
+		sortColumn = comicsRatingColumn
+	}
+
 	query := fmt.Sprintf(
 		`
-		SELECT count(*) OVER(), id, created_at, title, year, pages, version
+		SELECT count(*) OVER(), id, created_at, title, summary, year, pages, series_id, issue_number, publisher_id, imprint_id,
+		       cover_urls, archive_key, price, release_date, on_sale_date, foc_date, item_code, upc, %s, %s, %s, version
 		FROM comics
-		WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		AND (year = $2 OR $2 = -1)
+		%s
 		ORDER BY %s %s, id ASC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $9 OFFSET $10`, comicsStockColumn, comicsRatingColumn, comicsReviewCountColumn, comicsWhereClause, sortColumn, filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{title, year, filters.limit(), filters.offset()}
+	args := append(filter.args(), filters.limit(), filters.offset())
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -166,13 +566,30 @@ func (m ComicsModel) GetAll(title string, year int, filters Filters) ([]*Comics,
 			&comic.ID,
 			&comic.CreatedAt,
 			&comic.Title,
+			&comic.Summary,
 			&comic.Year,
 			&comic.Pages,
+			&comic.SeriesID,
+			&comic.IssueNumber,
+			&comic.PublisherID,
+			&comic.ImprintID,
+			&comic.CoverURLs,
+			&comic.ArchiveKey,
+			&comic.Price,
+			&comic.ReleaseDate,
+			&comic.OnSaleDate,
+			&comic.FOCDate,
+			&comic.ItemCode,
+			&comic.UPC,
+			&comic.Stock,
+			&comic.Rating,
+			&comic.ReviewCount,
 			&comic.Version,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
+		comic.Available = comic.Stock > 0
 		comics = append(comics, &comic)
 	}
 	if err = rows.Err(); err != nil {
@@ -182,3 +599,150 @@ func (m ComicsModel) GetAll(title string, year int, filters Filters) ([]*Comics,
 	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 	return comics, metadata, nil
 }
+
+// A Facet is the number of comics matching a listing's filters for a single value of a
+// facet dimension, such as a publisher.
+type Facet struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// GetPublisherFacets() counts the comics matching the given filter per publisher. The
+// publisher condition itself is deliberately left out, so that clients can show how many
+// results every other publisher would give.
+func (m ComicsModel) GetPublisherFacets(filter ComicsFilter) ([]Facet, error) {
+	filter.PublisherID = 0
+
+	query := fmt.Sprintf(
+		`
+		SELECT publishers.id, publishers.name, counts.total
+		FROM (
+			SELECT publisher_id, count(*) AS total
+			FROM comics
+			%s
+			AND publisher_id IS NOT NULL
+			GROUP BY publisher_id
+		) AS counts
+		INNER JOIN publishers ON publishers.id = counts.publisher_id
+		ORDER BY counts.total DESC, publishers.name ASC`, comicsWhereClause)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filter.args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := []Facet{}
+	for rows.Next() {
+		var facet Facet
+		err := rows.Scan(&facet.ID, &facet.Name, &facet.Count)
+		if err != nil {
+			return nil, err
+		}
+		facets = append(facets, facet)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// A ReleaseGroup lists the issues of a single publisher that go on sale in a period.
+type ReleaseGroup struct {
+	PublisherID *int64    `json:"publisher_id"`
+	Publisher   string    `json:"publisher"`
+	Comics      []*Comics `json:"comics"`
+}
+
+// comicsStreetDate is the date an issue reaches the shops: its on-sale date if known,
+// and its release date otherwise.
+const comicsStreetDate = `COALESCE(comics.on_sale_date, comics.release_date)`
+
+// GetReleases() lists the issues that go on sale from one date up to, but not including,
+// another, grouped by publisher in alphabetical order. Issues without a publisher come
+// last, in a group with a nil PublisherID.
+func (m ComicsModel) GetReleases(from, to Date) ([]*ReleaseGroup, error) {
+	query := fmt.Sprintf(`
+		SELECT comics.id, comics.created_at, comics.title, comics.summary, comics.year, comics.pages, comics.series_id,
+		       comics.issue_number, comics.publisher_id, comics.imprint_id, comics.cover_urls, comics.archive_key,
+		       comics.price, comics.release_date, comics.on_sale_date, comics.foc_date, comics.item_code, comics.upc, %s,
+		       comics.version,
+		       COALESCE(publishers.name, '')
+		FROM comics
+		LEFT JOIN publishers ON publishers.id = comics.publisher_id
+		WHERE %s >= $1 AND %s < $2
+		ORDER BY publishers.name ASC NULLS LAST, %s ASC, comics.title ASC, comics.issue_sort ASC, comics.id ASC`,
+		comicsStockColumn, comicsStreetDate, comicsStreetDate, comicsStreetDate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []*ReleaseGroup{}
+	var group *ReleaseGroup
+	for rows.Next() {
+		var comic Comics
+		var publisher string
+		err := rows.Scan(
+			&comic.ID,
+			&comic.CreatedAt,
+			&comic.Title,
+			&comic.Summary,
+			&comic.Year,
+			&comic.Pages,
+			&comic.SeriesID,
+			&comic.IssueNumber,
+			&comic.PublisherID,
+			&comic.ImprintID,
+			&comic.CoverURLs,
+			&comic.ArchiveKey,
+			&comic.Price,
+			&comic.ReleaseDate,
+			&comic.OnSaleDate,
+			&comic.FOCDate,
+			&comic.ItemCode,
+			&comic.UPC,
+			&comic.Stock,
+			&comic.Version,
+			&publisher,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if group == nil || !sameID(group.PublisherID, comic.PublisherID) {
+			group = &ReleaseGroup{PublisherID: comic.PublisherID, Publisher: publisher}
+			groups = append(groups, group)
+		}
+		comic.Available = comic.Stock > 0
+		group.Comics = append(group.Comics, &comic)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// StreetDate() returns the date the issue reaches the shops, or nil if it isn't known.
+func (c *Comics) StreetDate() *Date {
+	if c.OnSaleDate != nil {
+		return c.OnSaleDate
+	}
+	return c.ReleaseDate
+}
+
+func sameID(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
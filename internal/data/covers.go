@@ -0,0 +1,44 @@
+package data
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// CoverURLs maps a cover size name ("original", "small", "medium", "large") to the
+// public URL of the image in that size. It is stored in the jsonb cover_urls column.
+type CoverURLs map[string]string
+
+func (c CoverURLs) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *CoverURLs) Scan(src interface{}) error {
+	var source []byte
+	switch value := src.(type) {
+	case []byte:
+		source = value
+	case string:
+		source = []byte(value)
+	case nil:
+		*c = nil
+		return nil
+	default:
+		return errors.New("incompatible type for CoverURLs")
+	}
+
+	urls := CoverURLs{}
+	err := json.Unmarshal(source, &urls)
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		urls = nil
+	}
+	*c = urls
+	return nil
+}
@@ -0,0 +1,281 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/validator"
+	"strings"
+	"time"
+)
+
+// ErrUnknownCreator is returned when a credit references a creator that doesn't exist.
+var ErrUnknownCreator = errors.New("unknown creator")
+
+// The roles a creator can be credited with on a single issue.
+const (
+	RoleWriter      = "writer"
+	RolePenciller   = "penciller"
+	RoleInker       = "inker"
+	RoleColorist    = "colorist"
+	RoleLetterer    = "letterer"
+	RoleCoverArtist = "cover_artist"
+)
+
+var CreditRoles = []string{RoleWriter, RolePenciller, RoleInker, RoleColorist, RoleLetterer, RoleCoverArtist}
+
+type Creator struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	Version   int32     `json:"version"`
+}
+
+// A Credit links a creator to a comics issue in a specific role. The Name field is only
+// populated when reading credits back from the database.
+type Credit struct {
+	CreatorID int64  `json:"creator_id"`
+	Name      string `json:"name,omitempty"`
+	Role      string `json:"role"`
+}
+
+func ValidateCreator(v *validator.Validator, creator *Creator) {
+	v.Check(strings.TrimSpace(creator.Name) != "", "name", "must be provided")
+	v.Check(len(creator.Name) <= 500, "name", "must not be more than 500 bytes long")
+}
+
+func ValidateCredits(v *validator.Validator, credits []Credit) {
+	seen := make(map[string]bool)
+	for _, credit := range credits {
+		v.Check(credit.CreatorID > 0, "credits", "must reference a valid creator id")
+		v.Check(validator.In(credit.Role, CreditRoles...), "credits", "contains an invalid role")
+
+		key := fmt.Sprintf("%d:%s", credit.CreatorID, credit.Role)
+		v.Check(!seen[key], "credits", "must not contain duplicate credits")
+		seen[key] = true
+	}
+}
+
+type CreatorModel struct {
+	DB *sql.DB
+}
+
+func (m CreatorModel) Insert(creator *Creator) error {
+	query := `INSERT INTO creators (name)
+			VALUES ($1)
+			RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, creator.Name).Scan(&creator.ID, &creator.CreatedAt, &creator.Version)
+}
+
+func (m CreatorModel) Get(id int64) (*Creator, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `SELECT id, created_at, name, version
+			FROM creators
+			WHERE id = $1`
+
+	var creator Creator
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&creator.ID,
+		&creator.CreatedAt,
+		&creator.Name,
+		&creator.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &creator, nil
+}
+
+// GetByName() finds a creator by their exact name, ignoring case.
+func (m CreatorModel) GetByName(name string) (*Creator, error) {
+	query := `SELECT id, created_at, name, version
+			FROM creators
+			WHERE lower(name) = lower($1)
+			ORDER BY id
+			LIMIT 1`
+
+	var creator Creator
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, name).Scan(
+		&creator.ID,
+		&creator.CreatedAt,
+		&creator.Name,
+		&creator.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &creator, nil
+}
+
+func (m CreatorModel) Update(creator *Creator) error {
+	query := `UPDATE creators
+			SET name = $1, version = version + 1
+			WHERE id = $2 AND version = $3
+			RETURNING version`
+	args := []interface{}{creator.Name, creator.ID, creator.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&creator.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Deleting a creator also removes all of their credits thanks to the ON DELETE CASCADE
+// rule on the comics_creators table.
+func (m CreatorModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM creators
+			WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (m CreatorModel) GetAll(name string, filters Filters) ([]*Creator, Metadata, error) {
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), id, created_at, name, version
+		FROM creators
+		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	creators := []*Creator{}
+	for rows.Next() {
+		var creator Creator
+		err := rows.Scan(
+			&totalRecords,
+			&creator.ID,
+			&creator.CreatedAt,
+			&creator.Name,
+			&creator.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		creators = append(creators, &creator)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return creators, metadata, nil
+}
+
+// GetCredits() returns every credit for a specific comics issue, ordered by role and
+// then by creator name.
+func (m CreatorModel) GetCredits(comicsID int64) ([]Credit, error) {
+	query := `
+		SELECT creators.id, creators.name, comics_creators.role
+		FROM comics_creators
+		INNER JOIN creators ON creators.id = comics_creators.creator_id
+		WHERE comics_creators.comic_id = $1
+		ORDER BY comics_creators.role, creators.name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, comicsID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []Credit{}
+	for rows.Next() {
+		var credit Credit
+		err := rows.Scan(&credit.CreatorID, &credit.Name, &credit.Role)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, credit)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return credits, nil
+}
+
+// The setCredits() helper replaces all credits for a comics issue with the provided ones
+// within the given transaction, so that readers never see a partially updated list of
+// credits and the credits are saved together with the rest of the issue.
+func setCredits(ctx context.Context, tx *sql.Tx, comicsID int64, credits []Credit) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM comics_creators WHERE comic_id = $1`, comicsID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO comics_creators (comic_id, creator_id, role)
+		VALUES ($1, $2, $3)`
+	for _, credit := range credits {
+		_, err = tx.ExecContext(ctx, query, comicsID, credit.CreatorID, credit.Role)
+		if err != nil {
+			switch {
+			case strings.Contains(err.Error(), `violates foreign key constraint "comics_creators_creator_id_fkey"`):
+				return ErrUnknownCreator
+			default:
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidDateFormat = errors.New("invalid date format")
+	ErrInvalidWeekFormat = errors.New("invalid week format")
+)
+
+const dateLayout = "2006-01-02"
+
+var weekRX = regexp.MustCompile(`^\d{4}-W\d{2}$`)
+
+// Date is a calendar day without a time of day, such as the date an issue goes on sale.
+// In JSON it is represented as a "2006-01-02" string, and it is stored in date columns.
+type Date struct {
+	time.Time
+}
+
+// NewDate() returns the Date of the given year, month and day.
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate() converts a "2006-01-02" string into a Date.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, ErrInvalidDateFormat
+	}
+	return Date{t}, nil
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+func (d *Date) UnmarshalJSON(jsonValue []byte) error {
+	value, err := strconv.Unquote(string(jsonValue))
+	if err != nil {
+		return ErrInvalidDateFormat
+	}
+
+	date, err := ParseDate(value)
+	if err != nil {
+		return err
+	}
+
+	*d = date
+	return nil
+}
+
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *Date) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case time.Time:
+		*d = NewDate(value.Date())
+		return nil
+	case []byte:
+		return d.scanString(string(value))
+	case string:
+		return d.scanString(value)
+	default:
+		return errors.New("incompatible type for Date")
+	}
+}
+
+func (d *Date) scanString(s string) error {
+	if len(s) > len(dateLayout) {
+		s = s[:len(dateLayout)]
+	}
+	date, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
+
+// ParseWeek() converts an ISO 8601 week such as "2026-W42" into the Monday that starts
+// the week.
+func ParseWeek(s string) (Date, error) {
+	if !weekRX.MatchString(s) {
+		return Date{}, ErrInvalidWeekFormat
+	}
+	year, _ := strconv.Atoi(s[:4])
+	week, _ := strconv.Atoi(s[6:])
+
+	// January 4th always falls in the first week of the ISO year.
+	jan4 := NewDate(year, time.January, 4)
+	monday := jan4.StartOfWeek().AddDays((week - 1) * 7)
+
+	if y, w := monday.ISOWeek(); week < 1 || y != year || w != week {
+		return Date{}, ErrInvalidWeekFormat
+	}
+	return monday, nil
+}
+
+// StartOfWeek() returns the Monday of the ISO 8601 week the date falls in.
+func (d Date) StartOfWeek() Date {
+	return d.AddDays(-((int(d.Weekday()) + 6) % 7))
+}
+
+// AddDays() returns the date the given number of days later.
+func (d Date) AddDays(days int) Date {
+	return Date{d.AddDate(0, 0, days)}
+}
+
+// Week() returns the ISO 8601 week the date falls in, such as "2026-W42".
+func (d Date) Week() string {
+	year, week := d.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// Today() returns the current date in UTC.
+func Today() Date {
+	return NewDate(time.Now().UTC().Date())
+}
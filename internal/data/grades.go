@@ -0,0 +1,78 @@
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Grade is the condition of a physical copy, either as a number on the 10-point scale
+// used by CGC, such as "9.8", or as a raw grade such as "VF/NM".
+type Grade string
+
+// numericGrades are the steps of the 10-point scale. Grades in between, such as 9.7,
+// aren't awarded.
+var numericGrades = []float64{
+	0.5, 1.0, 1.5, 1.8, 2.0, 2.5, 3.0, 3.5, 4.0, 4.5, 5.0, 5.5, 6.0, 6.5,
+	7.0, 7.5, 8.0, 8.5, 9.0, 9.2, 9.4, 9.6, 9.8, 9.9, 10.0,
+}
+
+// rawGrades maps the raw grades to the step of the 10-point scale they stand for.
+var rawGrades = map[Grade]float64{
+	"PR":    0.5,
+	"FR":    1.0,
+	"FR/GD": 1.5,
+	"GD-":   1.8,
+	"GD":    2.0,
+	"GD+":   2.5,
+	"GD/VG": 3.0,
+	"VG-":   3.5,
+	"VG":    4.0,
+	"VG+":   4.5,
+	"VG/FN": 5.0,
+	"FN-":   5.5,
+	"FN":    6.0,
+	"FN+":   6.5,
+	"FN/VF": 7.0,
+	"VF-":   7.5,
+	"VF":    8.0,
+	"VF+":   8.5,
+	"VF/NM": 9.0,
+	"NM-":   9.2,
+	"NM":    9.4,
+	"NM+":   9.6,
+	"NM/MT": 9.8,
+	"MT":    9.9,
+	"GM":    10.0,
+}
+
+// NormalizeGrade() converts a grade as a user typed it into its usual spelling: raw
+// grades in upper case without spaces, and numbers with a single decimal place, so that
+// "vf / nm" becomes "VF/NM" and "10" becomes "10.0". Anything that isn't a grade is
+// returned unchanged apart from surrounding spaces.
+func NormalizeGrade(s string) Grade {
+	s = strings.TrimSpace(s)
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return Grade(fmt.Sprintf("%.1f", f))
+	}
+	return Grade(strings.ToUpper(strings.ReplaceAll(s, " ", "")))
+}
+
+// Numeric() returns the step of the 10-point scale the grade stands for, and whether
+// the grade is valid at all.
+func (g Grade) Numeric() (float64, bool) {
+	if value, ok := rawGrades[g]; ok {
+		return value, true
+	}
+
+	f, err := strconv.ParseFloat(string(g), 64)
+	if err != nil {
+		return 0, false
+	}
+	for _, value := range numericGrades {
+		if f == value {
+			return value, true
+		}
+	}
+	return 0, false
+}
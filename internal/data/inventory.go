@@ -0,0 +1,428 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/miras210/finalGolang/internal/validator"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDuplicateInventory = errors.New("duplicate inventory item")
+	ErrInsufficientStock  = errors.New("insufficient stock")
+)
+
+// The reasons a stock adjustment can be recorded with.
+var AdjustmentReasons = []string{"received", "sold", "returned", "damaged", "lost", "recount"}
+
+// An InventoryItem counts the copies of an issue (or one of its cover variants) held at
+// a single location. Reserved copies are on hand, but promised to a customer, so only
+// OnHand - Reserved copies are available for sale.
+type InventoryItem struct {
+	ID           int64     `json:"id"`
+	CreatedAt    time.Time `json:"-"`
+	ComicsID     int64     `json:"comics_id"`
+	VariantID    *int64    `json:"variant_id,omitempty"`
+	Location     string    `json:"location"`
+	OnHand       int32     `json:"on_hand"`
+	Reserved     int32     `json:"reserved"`
+	ReorderPoint int32     `json:"reorder_point"`
+	Version      int32     `json:"version"`
+}
+
+func (i *InventoryItem) Available() int32 {
+	return i.OnHand - i.Reserved
+}
+
+// NeedsReorder() reports whether the available stock has dropped to the reorder point.
+func (i *InventoryItem) NeedsReorder() bool {
+	return i.Available() <= i.ReorderPoint
+}
+
+// MarshalJSON() adds the computed available count and reorder flag to the JSON
+// representation of an item.
+func (i *InventoryItem) MarshalJSON() ([]byte, error) {
+	type item InventoryItem
+	return json.Marshal(struct {
+		*item
+		Available    int32 `json:"available"`
+		NeedsReorder bool  `json:"needs_reorder"`
+	}{(*item)(i), i.Available(), i.NeedsReorder()})
+}
+
+// An Adjustment records a change to the on-hand count of an inventory item.
+type Adjustment struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	InventoryID int64     `json:"inventory_id"`
+	UserID      *int64    `json:"user_id,omitempty"`
+	Delta       int32     `json:"delta"`
+	Reason      string    `json:"reason"`
+	Note        string    `json:"note,omitempty"`
+}
+
+func ValidateInventoryItem(v *validator.Validator, item *InventoryItem) {
+	v.Check(strings.TrimSpace(item.Location) != "", "location", "must be provided")
+	v.Check(len(item.Location) <= 100, "location", "must not be more than 100 bytes long")
+
+	v.Check(item.OnHand >= 0, "on_hand", "must not be negative")
+	v.Check(item.ReorderPoint >= 0, "reorder_point", "must not be negative")
+}
+
+func ValidateAdjustment(v *validator.Validator, adjustment *Adjustment) {
+	v.Check(adjustment.Delta != 0, "delta", "must not be zero")
+	v.Check(validator.In(adjustment.Reason, AdjustmentReasons...), "reason", "must be one of received, sold, returned, damaged, lost or recount")
+	v.Check(len(adjustment.Note) <= 1000, "note", "must not be more than 1000 bytes long")
+}
+
+func ValidateQuantity(v *validator.Validator, quantity int32) {
+	v.Check(quantity > 0, "quantity", "must be a positive integer")
+	v.Check(quantity <= 1000, "quantity", "must not be more than 1000")
+}
+
+type InventoryModel struct {
+	DB *sql.DB
+}
+
+// Insert() adds a new inventory item. Its initial on-hand count is recorded as a
+// "received" adjustment by the given user in the same transaction, so that the adjustment
+// history of an item always adds up to its on-hand count.
+func (m InventoryModel) Insert(item *InventoryItem, userID *int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO inventory (comic_id, variant_id, location, on_hand, reorder_point)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at, version`
+
+	args := []interface{}{item.ComicsID, item.VariantID, item.Location, item.OnHand, item.ReorderPoint}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&item.ID, &item.CreatedAt, &item.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "inventory_item_location_idx"`:
+			return ErrDuplicateInventory
+		default:
+			return err
+		}
+	}
+
+	if item.OnHand > 0 {
+		query = `INSERT INTO stock_adjustments (inventory_id, user_id, delta, reason, note)
+				VALUES ($1, $2, $3, 'received', 'initial stock')`
+
+		_, err = tx.ExecContext(ctx, query, item.ID, userID, item.OnHand)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m InventoryModel) Get(id int64) (*InventoryItem, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `SELECT id, created_at, comic_id, variant_id, location, on_hand, reserved, reorder_point, version
+			FROM inventory
+			WHERE id = $1`
+
+	var item InventoryItem
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&item.ID,
+		&item.CreatedAt,
+		&item.ComicsID,
+		&item.VariantID,
+		&item.Location,
+		&item.OnHand,
+		&item.Reserved,
+		&item.ReorderPoint,
+		&item.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &item, nil
+}
+
+// GetAllForComics() returns the inventory of an issue and its variants at every location.
+func (m InventoryModel) GetAllForComics(comicsID int64) ([]*InventoryItem, error) {
+	query := `SELECT id, created_at, comic_id, variant_id, location, on_hand, reserved, reorder_point, version
+			FROM inventory
+			WHERE comic_id = $1
+			ORDER BY variant_id NULLS FIRST, location, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, comicsID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*InventoryItem{}
+	for rows.Next() {
+		var item InventoryItem
+		err := rows.Scan(
+			&item.ID,
+			&item.CreatedAt,
+			&item.ComicsID,
+			&item.VariantID,
+			&item.Location,
+			&item.OnHand,
+			&item.Reserved,
+			&item.ReorderPoint,
+			&item.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetForLocation() returns the inventory item for the main cover of an issue at a
+// location.
+func (m InventoryModel) GetForLocation(comicsID int64, location string) (*InventoryItem, error) {
+	query := `SELECT id, created_at, comic_id, variant_id, location, on_hand, reserved, reorder_point, version
+			FROM inventory
+			WHERE comic_id = $1 AND variant_id IS NULL AND location = $2`
+
+	var item InventoryItem
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, comicsID, location).Scan(
+		&item.ID,
+		&item.CreatedAt,
+		&item.ComicsID,
+		&item.VariantID,
+		&item.Location,
+		&item.OnHand,
+		&item.Reserved,
+		&item.ReorderPoint,
+		&item.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &item, nil
+}
+
+// Update() changes the location and reorder point of an item. Stock levels can only be
+// changed through Reserve(), Release() and Adjust().
+func (m InventoryModel) Update(item *InventoryItem) error {
+	query := `UPDATE inventory
+			SET location = $1, reorder_point = $2, version = version + 1
+			WHERE id = $3 AND version = $4
+			RETURNING version`
+	args := []interface{}{item.Location, item.ReorderPoint, item.ID, item.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&item.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "inventory_item_location_idx"`:
+			return ErrDuplicateInventory
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m InventoryModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM inventory
+			WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Reserve() sets aside quantity available copies of an item. Like ComicsModel.Update()
+// the change only applies if the item's version hasn't changed since it was read, so
+// two concurrent reservations can never promise the same copy twice.
+func (m InventoryModel) Reserve(item *InventoryItem, quantity int32) error {
+	if item.Available() < quantity {
+		return ErrInsufficientStock
+	}
+
+	query := `UPDATE inventory
+			SET reserved = reserved + $1, version = version + 1
+			WHERE id = $2 AND version = $3
+			RETURNING reserved, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, quantity, item.ID, item.Version).Scan(&item.Reserved, &item.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Release() returns quantity reserved copies of an item to the available stock.
+func (m InventoryModel) Release(item *InventoryItem, quantity int32) error {
+	if item.Reserved < quantity {
+		return ErrInsufficientStock
+	}
+
+	query := `UPDATE inventory
+			SET reserved = reserved - $1, version = version + 1
+			WHERE id = $2 AND version = $3
+			RETURNING reserved, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, quantity, item.ID, item.Version).Scan(&item.Reserved, &item.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Adjust() changes the on-hand count of an item by adjustment.Delta and records the
+// adjustment, both in a single transaction. The count can't drop below the number of
+// reserved copies.
+func (m InventoryModel) Adjust(item *InventoryItem, adjustment *Adjustment) error {
+	if item.OnHand+adjustment.Delta < item.Reserved {
+		return ErrInsufficientStock
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE inventory
+			SET on_hand = on_hand + $1, version = version + 1
+			WHERE id = $2 AND version = $3
+			RETURNING on_hand, version`
+
+	err = tx.QueryRowContext(ctx, query, adjustment.Delta, item.ID, item.Version).Scan(&item.OnHand, &item.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	query = `INSERT INTO stock_adjustments (inventory_id, user_id, delta, reason, note)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at`
+
+	adjustment.InventoryID = item.ID
+	args := []interface{}{adjustment.InventoryID, adjustment.UserID, adjustment.Delta, adjustment.Reason, adjustment.Note}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&adjustment.ID, &adjustment.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAdjustments() returns the adjustment history of an item, most recent first.
+func (m InventoryModel) GetAdjustments(inventoryID int64) ([]*Adjustment, error) {
+	query := `SELECT id, created_at, inventory_id, user_id, delta, reason, note
+			FROM stock_adjustments
+			WHERE inventory_id = $1
+			ORDER BY created_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, inventoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	adjustments := []*Adjustment{}
+	for rows.Next() {
+		var adjustment Adjustment
+		err := rows.Scan(
+			&adjustment.ID,
+			&adjustment.CreatedAt,
+			&adjustment.InventoryID,
+			&adjustment.UserID,
+			&adjustment.Delta,
+			&adjustment.Reason,
+			&adjustment.Note,
+		)
+		if err != nil {
+			return nil, err
+		}
+		adjustments = append(adjustments, &adjustment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return adjustments, nil
+}
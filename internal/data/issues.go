@@ -0,0 +1,59 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	issueNumberRX   = regexp.MustCompile(`^(.*?)\s*(\d+(?:\.\d+)?)?$`)
+	issueFractionRX = regexp.MustCompile(`^(\d+)/(\d+)$`)
+)
+
+// IssueNumber holds the printed number of an issue. It is deliberately a string, as real
+// world numbering includes values such as "0", "1.5", "1/2" or "Annual 2".
+type IssueNumber string
+
+// SortKey() returns a key which sorts issue numbers in their natural reading order when
+// compared byte by byte (the issue_sort column uses the "C" collation for this reason).
+// Plain numbers come first in numeric order, followed by prefixed numbers such as
+// "Annual 1", "Annual 2" grouped by their prefix.
+func (n IssueNumber) SortKey() string {
+	s := strings.TrimSpace(string(n))
+
+	if m := issueFractionRX.FindStringSubmatch(s); m != nil {
+		numerator, _ := strconv.Atoi(m[1])
+		denominator, _ := strconv.Atoi(m[2])
+		if denominator != 0 {
+			value := float64(numerator) / float64(denominator)
+			return "#" + formatIssueValue(strconv.FormatFloat(value, 'f', 4, 64))
+		}
+	}
+
+	m := issueNumberRX.FindStringSubmatch(s)
+	prefix := strings.ToLower(strings.Join(strings.Fields(m[1]), " "))
+	if m[2] == "" {
+		return prefix + "#"
+	}
+	return prefix + "#" + formatIssueValue(m[2])
+}
+
+// formatIssueValue() zero-pads the integer part of a decimal number to 10 digits and its
+// fractional part to 4 digits, so that "2" < "10" < "10.5" also holds lexically.
+func formatIssueValue(s string) string {
+	parts := strings.SplitN(s, ".", 2)
+	integer, _ := strconv.ParseInt(parts[0], 10, 64)
+
+	fraction := ""
+	if len(parts) == 2 {
+		fraction = parts[1]
+	}
+	if len(fraction) > 4 {
+		fraction = fraction[:4]
+	}
+	fraction += strings.Repeat("0", 4-len(fraction))
+
+	return fmt.Sprintf("%010d.%s", integer, fraction)
+}
@@ -16,8 +16,26 @@ var (
 // like a UserModel and PermissionModel, as our build progresses.
 type Models struct {
 	Comics      ComicsModel
+	Variants    VariantModel
+	Ownership   OwnershipModel
+	Inventory   InventoryModel
+	Shipments   ShipmentModel
+	Carts       CartModel
+	Orders      OrderModel
+	Payments    PaymentModel
+	PullList    PullListModel
+	Preorders   PreorderModel
+	Collection  CollectionModel
+	Prices      PriceHistoryModel
+	Reviews     ReviewModel
+	Wishlist    WishlistModel
+	ReadStates  ReadStateModel
+	Creators    CreatorModel
+	Series      SeriesModel
+	Publishers  PublisherModel
 	Users       UserModel
 	Tokens      TokenModel
+	APIKeys     APIKeyModel
 	Permissions PermissionModel
 }
 
@@ -26,8 +44,26 @@ type Models struct {
 func NewModels(db *sql.DB) Models {
 	return Models{
 		Comics:      ComicsModel{DB: db},
+		Variants:    VariantModel{DB: db},
+		Ownership:   OwnershipModel{DB: db},
+		Inventory:   InventoryModel{DB: db},
+		Shipments:   ShipmentModel{DB: db},
+		Carts:       CartModel{DB: db},
+		Orders:      OrderModel{DB: db},
+		Payments:    PaymentModel{DB: db},
+		PullList:    PullListModel{DB: db},
+		Preorders:   PreorderModel{DB: db},
+		Collection:  CollectionModel{DB: db},
+		Prices:      PriceHistoryModel{DB: db},
+		Reviews:     ReviewModel{DB: db},
+		Wishlist:    WishlistModel{DB: db},
+		ReadStates:  ReadStateModel{DB: db},
+		Creators:    CreatorModel{DB: db},
+		Series:      SeriesModel{DB: db},
+		Publishers:  PublisherModel{DB: db},
 		Users:       UserModel{DB: db},
 		Tokens:      TokenModel{DB: db},
+		APIKeys:     APIKeyModel{DB: db},
 		Permissions: PermissionModel{DB: db},
 	}
 }
@@ -0,0 +1,382 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrEmptyCart         = errors.New("cart is empty")
+	ErrInvalidTransition = errors.New("invalid order status transition")
+)
+
+// The statuses an order moves through.
+const (
+	OrderPending   = "pending"
+	OrderPaid      = "paid"
+	OrderPicking   = "picking"
+	OrderShipped   = "shipped"
+	OrderCancelled = "cancelled"
+	OrderRefunded  = "refunded"
+)
+
+var OrderStatuses = []string{OrderPending, OrderPaid, OrderPicking, OrderShipped, OrderCancelled, OrderRefunded}
+
+// orderTransitions lists the statuses an order can move to from each status. Cancelled
+// and refunded orders are final.
+var orderTransitions = map[string][]string{
+	OrderPending: {OrderPaid, OrderCancelled},
+	OrderPaid:    {OrderPicking, OrderRefunded},
+	OrderPicking: {OrderShipped, OrderRefunded},
+	OrderShipped: {OrderRefunded},
+}
+
+type Order struct {
+	ID        int64        `json:"id"`
+	CreatedAt time.Time    `json:"created_at"`
+	UserID    *int64       `json:"user_id,omitempty"`
+	Status    string       `json:"status"`
+	Total     Price        `json:"total"`
+	Items     []*OrderItem `json:"items,omitempty"`
+	Version   int32        `json:"version"`
+}
+
+// CanTransition() reports whether the order is allowed to move to the given status.
+func (o *Order) CanTransition(status string) bool {
+	for _, next := range orderTransitions[o.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// An OrderItem is a line of an order. The title and unit price are copied from the cart
+// at checkout, so later catalogue changes don't alter past orders.
+type OrderItem struct {
+	ID        int64  `json:"id"`
+	ComicsID  *int64 `json:"comics_id,omitempty"`
+	VariantID *int64 `json:"variant_id,omitempty"`
+	Title     string `json:"title"`
+	UnitPrice Price  `json:"unit_price"`
+	Quantity  int32  `json:"quantity"`
+}
+
+type OrderModel struct {
+	DB *sql.DB
+}
+
+// Checkout() turns the cart of a user into a pending order. In the same transaction the
+// ordered copies are taken out of stock, starting with the inventory locations that have
+// the most copies available, and the cart is emptied. If any item doesn't have enough
+// stock nothing is changed and ErrInsufficientStock is returned.
+func (m OrderModel) Checkout(userID int64) (*Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cartItems, err := getCartItems(ctx, tx, ` ORDER BY cart_items.id FOR UPDATE OF cart_items`, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cartItems) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	order := &Order{UserID: &userID, Status: OrderPending}
+	for _, item := range cartItems {
+		order.Total += item.UnitPrice * Price(item.Quantity)
+	}
+
+	query := `INSERT INTO orders (user_id, status, total)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at, version`
+
+	err = tx.QueryRowContext(ctx, query, userID, order.Status, order.Total).Scan(&order.ID, &order.CreatedAt, &order.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cartItem := range cartItems {
+		item := &OrderItem{
+			ComicsID:  &cartItem.ComicsID,
+			VariantID: cartItem.VariantID,
+			Title:     cartItem.Title,
+			UnitPrice: cartItem.UnitPrice,
+			Quantity:  cartItem.Quantity,
+		}
+
+		query = `INSERT INTO order_items (order_id, comic_id, variant_id, title, unit_price, quantity)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING id`
+
+		args := []interface{}{order.ID, item.ComicsID, item.VariantID, item.Title, item.UnitPrice, item.Quantity}
+
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&item.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = allocateStock(ctx, tx, order, item, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		order.Items = append(order.Items, item)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM cart_items WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// The allocateStock() helper takes the copies of an order item out of the inventory,
+// recording a "sold" stock adjustment and an allocation for every inventory item used.
+func allocateStock(ctx context.Context, tx *sql.Tx, order *Order, item *OrderItem, userID int64) error {
+	query := `SELECT id, on_hand - reserved
+			FROM inventory
+			WHERE comic_id = $1 AND COALESCE(variant_id, 0) = COALESCE($2::bigint, 0) AND on_hand > reserved
+			ORDER BY on_hand - reserved DESC, id
+			FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, item.ComicsID, item.VariantID)
+	if err != nil {
+		return err
+	}
+
+	type allocation struct {
+		inventoryID int64
+		quantity    int32
+	}
+
+	var allocations []allocation
+	remaining := item.Quantity
+	for rows.Next() && remaining > 0 {
+		var a allocation
+		var available int32
+		err := rows.Scan(&a.inventoryID, &available)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		a.quantity = available
+		if a.quantity > remaining {
+			a.quantity = remaining
+		}
+		remaining -= a.quantity
+		allocations = append(allocations, a)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return ErrInsufficientStock
+	}
+
+	for _, a := range allocations {
+		_, err = tx.ExecContext(ctx, `UPDATE inventory SET on_hand = on_hand - $1, version = version + 1 WHERE id = $2`, a.quantity, a.inventoryID)
+		if err != nil {
+			return err
+		}
+
+		query = `INSERT INTO stock_adjustments (inventory_id, user_id, delta, reason, note)
+				VALUES ($1, $2, $3, 'sold', $4)`
+		_, err = tx.ExecContext(ctx, query, a.inventoryID, userID, -a.quantity, fmt.Sprintf("order #%d", order.ID))
+		if err != nil {
+			return err
+		}
+
+		query = `INSERT INTO order_allocations (order_item_id, inventory_id, quantity)
+				VALUES ($1, $2, $3)`
+		_, err = tx.ExecContext(ctx, query, item.ID, a.inventoryID, a.quantity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get() fetches an order together with its items.
+func (m OrderModel) Get(id int64) (*Order, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `SELECT id, created_at, user_id, status, total, version
+			FROM orders
+			WHERE id = $1`
+
+	var order Order
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&order.ID,
+		&order.CreatedAt,
+		&order.UserID,
+		&order.Status,
+		&order.Total,
+		&order.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query = `SELECT id, comic_id, variant_id, title, unit_price, quantity
+			FROM order_items
+			WHERE order_id = $1
+			ORDER BY id`
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item OrderItem
+		err := rows.Scan(&item.ID, &item.ComicsID, &item.VariantID, &item.Title, &item.UnitPrice, &item.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		order.Items = append(order.Items, &item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetAll() lists orders without their items. A userID of -1 lists the orders of every
+// user, and an empty status lists orders in any status.
+func (m OrderModel) GetAll(userID int64, status string, filters Filters) ([]*Order, Metadata, error) {
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), id, created_at, user_id, status, total, version
+		FROM orders
+		WHERE (user_id = $1 OR $1 = -1)
+		AND (status = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, status, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	orders := []*Order{}
+	for rows.Next() {
+		var order Order
+		err := rows.Scan(
+			&totalRecords,
+			&order.ID,
+			&order.CreatedAt,
+			&order.UserID,
+			&order.Status,
+			&order.Total,
+			&order.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		orders = append(orders, &order)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return orders, metadata, nil
+}
+
+// UpdateStatus() moves an order to a new status, enforcing the allowed transitions and
+// the optimistic version check. When an order that hasn't shipped yet is cancelled or
+// refunded, its copies are put back into the inventory items they were taken from. The
+// userID is recorded with the stock adjustments, and is nil for automated changes.
+func (m OrderModel) UpdateStatus(order *Order, status string, userID *int64) error {
+	if !order.CanTransition(status) {
+		return ErrInvalidTransition
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE orders
+			SET status = $1, version = version + 1
+			WHERE id = $2 AND version = $3
+			RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, status, order.ID, order.Version).Scan(&order.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	restock := (status == OrderCancelled || status == OrderRefunded) && order.Status != OrderShipped
+	if restock {
+		query = `
+			WITH restocked AS (
+				UPDATE inventory
+				SET on_hand = inventory.on_hand + totals.quantity, version = inventory.version + 1
+				FROM (
+					SELECT order_allocations.inventory_id, sum(order_allocations.quantity) AS quantity
+					FROM order_allocations
+					INNER JOIN order_items ON order_items.id = order_allocations.order_item_id
+					WHERE order_items.order_id = $1
+					GROUP BY order_allocations.inventory_id
+				) AS totals
+				WHERE inventory.id = totals.inventory_id
+				RETURNING inventory.id, totals.quantity
+			)
+			INSERT INTO stock_adjustments (inventory_id, user_id, delta, reason, note)
+			SELECT id, $2, quantity, 'returned', $3
+			FROM restocked`
+
+		_, err = tx.ExecContext(ctx, query, order.ID, userID, fmt.Sprintf("order #%d %s", order.ID, status))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	order.Status = status
+	return nil
+}
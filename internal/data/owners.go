@@ -0,0 +1,76 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrUnknownUser = errors.New("unknown user")
+
+// OwnershipModel records which users own a digital copy of which issues.
+type OwnershipModel struct {
+	DB *sql.DB
+}
+
+// Grant() gives a user a digital copy of an issue. Granting a copy the user already
+// owns is not an error.
+func (m OwnershipModel) Grant(userID, comicsID int64) error {
+	query := `
+		INSERT INTO comics_owners (user_id, comic_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, comicsID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `violates foreign key constraint "comics_owners_user_id_fkey"`):
+			return ErrUnknownUser
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m OwnershipModel) Revoke(userID, comicsID int64) error {
+	query := `
+		DELETE FROM comics_owners
+		WHERE user_id = $1 AND comic_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, comicsID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Owns() reports whether a user owns a digital copy of an issue.
+func (m OwnershipModel) Owns(userID, comicsID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM comics_owners WHERE user_id = $1 AND comic_id = $2
+		)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var owns bool
+	err := m.DB.QueryRowContext(ctx, query, userID, comicsID).Scan(&owns)
+	return owns, err
+}
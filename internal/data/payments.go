@@ -0,0 +1,197 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrDuplicatePayment = errors.New("duplicate payment")
+
+// A Payment records a single attempt to pay for an order through a payment provider,
+// including attempts that were declined.
+type Payment struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UserID      *int64    `json:"user_id,omitempty"`
+	OrderID     int64     `json:"order_id"`
+	Amount      Price     `json:"amount"`
+	Currency    string    `json:"currency"`
+	Provider    string    `json:"provider"`
+	Reference   string    `json:"reference,omitempty"`
+	Status      string    `json:"status"`
+	DeclineCode string    `json:"decline_code,omitempty"`
+	CardLast4   string    `json:"card_last4,omitempty"`
+	Version     int32     `json:"version"`
+}
+
+type PaymentModel struct {
+	DB *sql.DB
+}
+
+// Insert() records a new payment. An order can only have one payment that is pending,
+// authorized or captured at a time, so that two concurrent attempts to pay for the same
+// order can't both reach the provider; the second gets ErrDuplicatePayment.
+func (m PaymentModel) Insert(payment *Payment) error {
+	query := `INSERT INTO payments (user_id, order_id, amount, currency, provider, reference, status, decline_code, card_last4)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id, created_at, version`
+
+	args := []interface{}{
+		payment.UserID,
+		payment.OrderID,
+		payment.Amount,
+		payment.Currency,
+		payment.Provider,
+		payment.Reference,
+		payment.Status,
+		payment.DeclineCode,
+		payment.CardLast4,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&payment.ID, &payment.CreatedAt, &payment.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "payments_order_open_idx"`:
+			return ErrDuplicatePayment
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+const paymentColumns = `id, created_at, user_id, order_id, amount, currency, provider, reference, status, decline_code,
+		card_last4, version`
+
+func scanPayment(row interface{ Scan(...interface{}) error }, payment *Payment, extra ...interface{}) error {
+	dest := append(extra,
+		&payment.ID,
+		&payment.CreatedAt,
+		&payment.UserID,
+		&payment.OrderID,
+		&payment.Amount,
+		&payment.Currency,
+		&payment.Provider,
+		&payment.Reference,
+		&payment.Status,
+		&payment.DeclineCode,
+		&payment.CardLast4,
+		&payment.Version,
+	)
+	return row.Scan(dest...)
+}
+
+func (m PaymentModel) Get(id int64) (*Payment, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := fmt.Sprintf(`SELECT %s
+			FROM payments
+			WHERE id = $1`, paymentColumns)
+
+	var payment Payment
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanPayment(m.DB.QueryRowContext(ctx, query, id), &payment)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &payment, nil
+}
+
+// GetByReference() finds a payment by the reference its provider knows it by.
+func (m PaymentModel) GetByReference(provider, reference string) (*Payment, error) {
+	query := fmt.Sprintf(`SELECT %s
+			FROM payments
+			WHERE provider = $1 AND reference = $2 AND reference <> ''`, paymentColumns)
+
+	var payment Payment
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanPayment(m.DB.QueryRowContext(ctx, query, provider, reference), &payment)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &payment, nil
+}
+
+// GetAll() lists payments, newest first by default. A userID or orderID of -1 disables
+// the corresponding filter.
+func (m PaymentModel) GetAll(userID, orderID int64, filters Filters) ([]*Payment, Metadata, error) {
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), %s
+		FROM payments
+		WHERE (user_id = $1 OR $1 = -1)
+		AND (order_id = $2 OR $2 = -1)
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, paymentColumns, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, orderID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	payments := []*Payment{}
+	for rows.Next() {
+		var payment Payment
+		err := scanPayment(rows, &payment, &totalRecords)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		payments = append(payments, &payment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return payments, metadata, nil
+}
+
+// UpdateStatus() records a new provider status and reference for a payment, using the
+// same optimistic version check as the other models.
+func (m PaymentModel) UpdateStatus(payment *Payment) error {
+	query := `UPDATE payments
+			SET status = $1, decline_code = $2, reference = $3, version = version + 1
+			WHERE id = $4 AND version = $5
+			RETURNING version`
+	args := []interface{}{payment.Status, payment.DeclineCode, payment.Reference, payment.ID, payment.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&payment.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
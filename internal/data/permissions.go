@@ -23,6 +23,17 @@ func (p Permissions) Include(code string) bool {
 	return false
 }
 
+// Intersect() returns the permission codes found in both slices.
+func (p Permissions) Intersect(other Permissions) Permissions {
+	var permissions Permissions
+	for _, code := range p {
+		if other.Include(code) {
+			permissions = append(permissions, code)
+		}
+	}
+	return permissions
+}
+
 // Define the PermissionModel type.
 type PermissionModel struct {
 	DB *sql.DB
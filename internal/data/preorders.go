@@ -0,0 +1,279 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/validator"
+	"time"
+)
+
+var ErrDuplicatePreorder = errors.New("duplicate pre-order")
+
+// MaxPreorderQuantity is the most copies of an issue a customer can pre-order.
+const MaxPreorderQuantity = 100
+
+// A Preorder asks the shop to order copies of an upcoming issue for a customer. The
+// title and dates are read from the issue whenever a pre-order is loaded.
+type Preorder struct {
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UserID     int64      `json:"-"`
+	ComicsID   int64      `json:"comics_id"`
+	Title      string     `json:"title"`
+	FOCDate    *Date      `json:"foc_date,omitempty"`
+	OnSaleDate *Date      `json:"on_sale_date,omitempty"`
+	Quantity   int32      `json:"quantity"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+	Version    int32      `json:"version"`
+	UserName   string     `json:"-"`
+	UserEmail  string     `json:"-"`
+}
+
+// A PreorderSummary counts the pre-orders of an upcoming issue, so that staff know how
+// many copies to order from the distributor before the final order cutoff.
+type PreorderSummary struct {
+	ComicsID    int64  `json:"comics_id"`
+	Title       string `json:"title"`
+	IssueNumber string `json:"issue_number,omitempty"`
+	FOCDate     *Date  `json:"foc_date,omitempty"`
+	OnSaleDate  *Date  `json:"on_sale_date,omitempty"`
+	Customers   int    `json:"customers"`
+	Copies      int    `json:"copies"`
+	FOCPassed   bool   `json:"foc_passed"`
+}
+
+// Open() reports whether the pre-order can still be placed or changed on the given day:
+// the issue must go on sale later, and its final order cutoff, if known, must not have
+// passed. The cutoff day itself is still open.
+func (p *Preorder) Open(today Date) bool {
+	if p.OnSaleDate == nil || !p.OnSaleDate.After(today.Time) {
+		return false
+	}
+	return p.FOCDate == nil || !today.After(p.FOCDate.Time)
+}
+
+// ValidatePreorder() checks the quantity of a pre-order and that its issue can still be
+// pre-ordered today. The dates of the pre-order must have been read from its issue.
+func ValidatePreorder(v *validator.Validator, preorder *Preorder) {
+	v.Check(preorder.Quantity > 0, "quantity", "must be a positive integer")
+	v.Check(preorder.Quantity <= MaxPreorderQuantity, "quantity", fmt.Sprintf("must not be more than %d", MaxPreorderQuantity))
+
+	today := Today()
+	switch {
+	case preorder.OnSaleDate == nil || !preorder.OnSaleDate.After(today.Time):
+		v.AddError("comics_id", "must reference an upcoming issue")
+	case !preorder.Open(today):
+		v.AddError("comics_id", fmt.Sprintf("the final order cutoff for this issue passed on %s", preorder.FOCDate))
+	}
+}
+
+type PreorderModel struct {
+	DB *sql.DB
+}
+
+func (m PreorderModel) Insert(preorder *Preorder) error {
+	query := `INSERT INTO preorders (user_id, comic_id, quantity)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{preorder.UserID, preorder.ComicsID, preorder.Quantity}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&preorder.ID, &preorder.CreatedAt, &preorder.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "preorders_user_comic_idx"`:
+			return ErrDuplicatePreorder
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// preordersQuery selects pre-orders together with the title and dates of their issue,
+// and the name and email address of the customer.
+const preordersQuery = `
+		SELECT preorders.id, preorders.created_at, preorders.user_id, preorders.comic_id, comics.title, comics.foc_date,
+		       COALESCE(comics.on_sale_date, comics.release_date), preorders.quantity, preorders.notified_at,
+		       preorders.version, users.name, users.email
+		FROM preorders
+		INNER JOIN comics ON comics.id = preorders.comic_id
+		INNER JOIN users ON users.id = preorders.user_id`
+
+func (m PreorderModel) getPreorders(suffix string, args ...interface{}) ([]*Preorder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, preordersQuery+suffix, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	preorders := []*Preorder{}
+	for rows.Next() {
+		var preorder Preorder
+		err := rows.Scan(
+			&preorder.ID,
+			&preorder.CreatedAt,
+			&preorder.UserID,
+			&preorder.ComicsID,
+			&preorder.Title,
+			&preorder.FOCDate,
+			&preorder.OnSaleDate,
+			&preorder.Quantity,
+			&preorder.NotifiedAt,
+			&preorder.Version,
+			&preorder.UserName,
+			&preorder.UserEmail,
+		)
+		if err != nil {
+			return nil, err
+		}
+		preorders = append(preorders, &preorder)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return preorders, nil
+}
+
+// Get() fetches a pre-order of a user. Pre-orders of other users are reported as not
+// found.
+func (m PreorderModel) Get(userID, id int64) (*Preorder, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	preorders, err := m.getPreorders(` WHERE preorders.id = $1 AND preorders.user_id = $2`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(preorders) == 0 {
+		return nil, ErrRecordNotFound
+	}
+	return preorders[0], nil
+}
+
+// GetAllForUser() lists the pre-orders of a user, the earliest on-sale dates first.
+func (m PreorderModel) GetAllForUser(userID int64) ([]*Preorder, error) {
+	return m.getPreorders(` WHERE preorders.user_id = $1
+		ORDER BY COALESCE(comics.on_sale_date, comics.release_date) NULLS LAST, comics.title, preorders.id`, userID)
+}
+
+// GetDue() returns up to limit pre-orders whose issue has gone on sale by the given day
+// and whose customer hasn't been notified yet.
+func (m PreorderModel) GetDue(today Date, limit int) ([]*Preorder, error) {
+	return m.getPreorders(` WHERE preorders.notified_at IS NULL
+		AND COALESCE(comics.on_sale_date, comics.release_date) <= $1
+		ORDER BY preorders.id
+		LIMIT $2`, today, limit)
+}
+
+func (m PreorderModel) Update(preorder *Preorder) error {
+	query := `UPDATE preorders
+			SET quantity = $1, version = version + 1
+			WHERE id = $2 AND version = $3
+			RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, preorder.Quantity, preorder.ID, preorder.Version).Scan(&preorder.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// MarkNotified() records that the customer of a pre-order was told the issue is on sale.
+func (m PreorderModel) MarkNotified(preorder *Preorder) error {
+	query := `UPDATE preorders
+			SET notified_at = NOW()
+			WHERE id = $1
+			RETURNING notified_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, preorder.ID).Scan(&preorder.NotifiedAt)
+}
+
+func (m PreorderModel) Delete(userID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM preorders
+			WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetSummary() counts the customers and copies pre-ordered for every issue that hasn't
+// gone on sale yet, ordered by final order cutoff so the most urgent come first.
+func (m PreorderModel) GetSummary() ([]*PreorderSummary, error) {
+	query := `
+		SELECT comics.id, comics.title, comics.issue_number, comics.foc_date,
+		       COALESCE(comics.on_sale_date, comics.release_date), count(*), sum(preorders.quantity)
+		FROM preorders
+		INNER JOIN comics ON comics.id = preorders.comic_id
+		WHERE COALESCE(comics.on_sale_date, comics.release_date) > $1
+		GROUP BY comics.id
+		ORDER BY comics.foc_date NULLS LAST, COALESCE(comics.on_sale_date, comics.release_date), comics.title, comics.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	today := Today()
+	rows, err := m.DB.QueryContext(ctx, query, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []*PreorderSummary{}
+	for rows.Next() {
+		var summary PreorderSummary
+		err := rows.Scan(
+			&summary.ComicsID,
+			&summary.Title,
+			&summary.IssueNumber,
+			&summary.FOCDate,
+			&summary.OnSaleDate,
+			&summary.Customers,
+			&summary.Copies,
+		)
+		if err != nil {
+			return nil, err
+		}
+		summary.FOCPassed = summary.FOCDate != nil && today.After(summary.FOCDate.Time)
+		summaries = append(summaries, &summary)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// A PricePoint is the price of an issue, or of one of its variants, from its effective
+// date until the next price point of the same issue or variant.
+type PricePoint struct {
+	ComicsID      int64     `json:"comics_id"`
+	VariantID     *int64    `json:"variant_id,omitempty"`
+	Price         Price     `json:"price"`
+	EffectiveDate Date      `json:"effective_date"`
+	RecordedAt    time.Time `json:"recorded_at"`
+}
+
+// The recordPrice() helper adds the current price of an issue, or of one of its variants
+// if variantID isn't nil, to the price history using the given transaction. Nothing is
+// recorded if the price didn't change since the last price point, and a change on the
+// same day as the last one replaces it, so the history keeps a single price per day.
+func recordPrice(ctx context.Context, tx *sql.Tx, comicsID int64, variantID *int64, price Price) error {
+	query := `
+		INSERT INTO comic_prices (comic_id, variant_id, price)
+		SELECT $1::bigint, $2::bigint, $3::bigint
+		WHERE $3 IS DISTINCT FROM (
+			SELECT price FROM comic_prices
+			WHERE comic_id = $1 AND variant_id IS NOT DISTINCT FROM $2
+			ORDER BY effective_date DESC
+			LIMIT 1)
+		ON CONFLICT (comic_id, (COALESCE(variant_id, 0)), effective_date)
+		DO UPDATE SET price = EXCLUDED.price, recorded_at = NOW()`
+
+	_, err := tx.ExecContext(ctx, query, comicsID, variantID, price)
+	return err
+}
+
+type PriceHistoryModel struct {
+	DB *sql.DB
+}
+
+// GetForComics() returns the price history of an issue and its variants, oldest first.
+// A variantID of -1 returns the history of the issue and all of its variants, 0 only
+// that of the issue itself, and anything else that of a single variant. The from and to
+// dates limit the history to the price points effective between them, inclusive.
+func (m PriceHistoryModel) GetForComics(comicsID, variantID int64, from, to *Date) ([]*PricePoint, error) {
+	query := `
+		SELECT comic_id, variant_id, price, effective_date, recorded_at
+		FROM comic_prices
+		WHERE comic_id = $1
+		AND (COALESCE(variant_id, 0) = $2 OR $2 = -1)
+		AND (effective_date >= $3 OR $3 IS NULL)
+		AND (effective_date <= $4 OR $4 IS NULL)
+		ORDER BY variant_id NULLS FIRST, effective_date`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, comicsID, variantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []*PricePoint{}
+	for rows.Next() {
+		var point PricePoint
+		err := rows.Scan(&point.ComicsID, &point.VariantID, &point.Price, &point.EffectiveDate, &point.RecordedAt)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, &point)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
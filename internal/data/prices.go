@@ -0,0 +1,70 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPriceFormat = errors.New("invalid price format")
+
+var priceRX = regexp.MustCompile(`^\d+(\.\d{1,2})?$`)
+
+// Price holds an amount of money in cents, which is also how it is stored in the
+// database. In JSON it is represented as a decimal string such as "3.99", so that
+// clients never have to deal with floating point rounding.
+type Price int64
+
+func (p Price) String() string {
+	return fmt.Sprintf("%d.%02d", p/100, p%100)
+}
+
+func (p Price) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(p.String())), nil
+}
+
+// UnmarshalJSON() accepts both a quoted decimal string ("3.99") and a bare JSON
+// number (3.99), as long as it has at most two decimal places.
+func (p *Price) UnmarshalJSON(jsonValue []byte) error {
+	value := string(jsonValue)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	price, err := ParsePrice(value)
+	if err != nil {
+		return err
+	}
+
+	*p = price
+	return nil
+}
+
+// ParsePrice() converts a decimal string such as "3.99" or "4" into a Price.
+func ParsePrice(s string) (Price, error) {
+	s = strings.TrimSpace(s)
+	if !priceRX.MatchString(s) {
+		return 0, ErrInvalidPriceFormat
+	}
+
+	parts := strings.SplitN(s, ".", 2)
+	units, err := strconv.ParseInt(parts[0], 10, 64)
+	// Refuse amounts whose value in cents would overflow, rather than wrapping around.
+	if err != nil || units > (math.MaxInt64-99)/100 {
+		return 0, ErrInvalidPriceFormat
+	}
+
+	var cents int64
+	if len(parts) == 2 {
+		fraction := parts[1]
+		if len(fraction) == 1 {
+			fraction += "0"
+		}
+		cents, _ = strconv.ParseInt(fraction, 10, 64)
+	}
+
+	return Price(units*100 + cents), nil
+}
@@ -0,0 +1,371 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/validator"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDuplicatePublisher = errors.New("duplicate publisher")
+	ErrDuplicateImprint   = errors.New("duplicate imprint")
+	ErrUnknownPublisher   = errors.New("unknown publisher")
+	ErrUnknownImprint     = errors.New("unknown imprint")
+)
+
+type Publisher struct {
+	ID        int64      `json:"id"`
+	CreatedAt time.Time  `json:"-"`
+	Name      string     `json:"name"`
+	Country   string     `json:"country,omitempty"`
+	Imprints  []*Imprint `json:"imprints,omitempty"`
+	Version   int32      `json:"version"`
+}
+
+// An Imprint is a brand under which a publisher releases some of its books, such as
+// Vertigo under DC.
+type Imprint struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"-"`
+	PublisherID int64     `json:"publisher_id"`
+	Name        string    `json:"name"`
+	Version     int32     `json:"version"`
+}
+
+func ValidatePublisher(v *validator.Validator, publisher *Publisher) {
+	v.Check(strings.TrimSpace(publisher.Name) != "", "name", "must be provided")
+	v.Check(len(publisher.Name) <= 500, "name", "must not be more than 500 bytes long")
+	v.Check(len(publisher.Country) <= 100, "country", "must not be more than 100 bytes long")
+}
+
+func ValidateImprint(v *validator.Validator, imprint *Imprint) {
+	v.Check(strings.TrimSpace(imprint.Name) != "", "name", "must be provided")
+	v.Check(len(imprint.Name) <= 500, "name", "must not be more than 500 bytes long")
+}
+
+type PublisherModel struct {
+	DB *sql.DB
+}
+
+func (m PublisherModel) Insert(publisher *Publisher) error {
+	query := `INSERT INTO publishers (name, country)
+			VALUES ($1, $2)
+			RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, publisher.Name, publisher.Country).Scan(
+		&publisher.ID,
+		&publisher.CreatedAt,
+		&publisher.Version,
+	)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "publishers_name_key"`:
+			return ErrDuplicatePublisher
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Get() returns a publisher together with all of its imprints.
+func (m PublisherModel) Get(id int64) (*Publisher, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `SELECT id, created_at, name, country, version
+			FROM publishers
+			WHERE id = $1`
+
+	var publisher Publisher
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&publisher.ID,
+		&publisher.CreatedAt,
+		&publisher.Name,
+		&publisher.Country,
+		&publisher.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	publisher.Imprints, err = m.GetImprints(publisher.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &publisher, nil
+}
+
+// GetByName() looks up a publisher by its exact name, ignoring case. Its imprints aren't
+// loaded.
+func (m PublisherModel) GetByName(name string) (*Publisher, error) {
+	query := `SELECT id, created_at, name, country, version
+			FROM publishers
+			WHERE name = $1`
+
+	var publisher Publisher
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, name).Scan(
+		&publisher.ID,
+		&publisher.CreatedAt,
+		&publisher.Name,
+		&publisher.Country,
+		&publisher.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &publisher, nil
+}
+
+func (m PublisherModel) Update(publisher *Publisher) error {
+	query := `UPDATE publishers
+			SET name = $1, country = $2, version = version + 1
+			WHERE id = $3 AND version = $4
+			RETURNING version`
+	args := []interface{}{publisher.Name, publisher.Country, publisher.ID, publisher.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&publisher.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "publishers_name_key"`:
+			return ErrDuplicatePublisher
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Deleting a publisher also deletes its imprints, while any comics that referenced them
+// are kept with their publisher_id and imprint_id set to NULL.
+func (m PublisherModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM publishers
+			WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (m PublisherModel) GetAll(name string, filters Filters) ([]*Publisher, Metadata, error) {
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), id, created_at, name, country, version
+		FROM publishers
+		WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	publishers := []*Publisher{}
+	for rows.Next() {
+		var publisher Publisher
+		err := rows.Scan(
+			&totalRecords,
+			&publisher.ID,
+			&publisher.CreatedAt,
+			&publisher.Name,
+			&publisher.Country,
+			&publisher.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		publishers = append(publishers, &publisher)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return publishers, metadata, nil
+}
+
+func (m PublisherModel) InsertImprint(imprint *Imprint) error {
+	query := `INSERT INTO imprints (publisher_id, name)
+			VALUES ($1, $2)
+			RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, imprint.PublisherID, imprint.Name).Scan(
+		&imprint.ID,
+		&imprint.CreatedAt,
+		&imprint.Version,
+	)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "imprints_publisher_id_name_key"`:
+			return ErrDuplicateImprint
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m PublisherModel) GetImprint(id int64) (*Imprint, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `SELECT id, created_at, publisher_id, name, version
+			FROM imprints
+			WHERE id = $1`
+
+	var imprint Imprint
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&imprint.ID,
+		&imprint.CreatedAt,
+		&imprint.PublisherID,
+		&imprint.Name,
+		&imprint.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &imprint, nil
+}
+
+func (m PublisherModel) GetImprints(publisherID int64) ([]*Imprint, error) {
+	query := `SELECT id, created_at, publisher_id, name, version
+			FROM imprints
+			WHERE publisher_id = $1
+			ORDER BY name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, publisherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imprints := []*Imprint{}
+	for rows.Next() {
+		var imprint Imprint
+		err := rows.Scan(
+			&imprint.ID,
+			&imprint.CreatedAt,
+			&imprint.PublisherID,
+			&imprint.Name,
+			&imprint.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		imprints = append(imprints, &imprint)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return imprints, nil
+}
+
+func (m PublisherModel) UpdateImprint(imprint *Imprint) error {
+	query := `UPDATE imprints
+			SET name = $1, version = version + 1
+			WHERE id = $2 AND version = $3
+			RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, imprint.Name, imprint.ID, imprint.Version).Scan(&imprint.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "imprints_publisher_id_name_key"`:
+			return ErrDuplicateImprint
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m PublisherModel) DeleteImprint(publisherID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM imprints
+			WHERE id = $1 AND publisher_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, publisherID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
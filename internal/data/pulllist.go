@@ -0,0 +1,245 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/miras210/finalGolang/internal/validator"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var ErrDuplicateSubscription = errors.New("duplicate pull list subscription")
+
+// A Subscription puts a series on the pull list of a user. It is matched on the
+// normalized series name rather than a series ID, so customers can subscribe to titles
+// that aren't in the catalogue yet.
+type Subscription struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UserID     int64     `json:"-"`
+	SeriesName string    `json:"series_name"`
+	SeriesKey  string    `json:"series_key"`
+}
+
+// A Hold is an issue that was set aside for a user because it matched their pull list.
+type Hold struct {
+	ID             int64     `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UserID         int64     `json:"-"`
+	ComicsID       int64     `json:"comics_id"`
+	SubscriptionID *int64    `json:"subscription_id,omitempty"`
+	Title          string    `json:"title"`
+	IssueNumber    string    `json:"issue_number,omitempty"`
+	UserName       string    `json:"-"`
+	UserEmail      string    `json:"-"`
+}
+
+// NormalizeSeriesName() reduces a series name to the key subscriptions are matched on:
+// it is lower-cased, punctuation is dropped, runs of white space are collapsed and a
+// leading "the" is removed, so "The Amazing Spider-Man" and "amazing spider man" match.
+func NormalizeSeriesName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(fields) > 1 && fields[0] == "the" {
+		fields = fields[1:]
+	}
+	return strings.Join(fields, " ")
+}
+
+func ValidateSubscription(v *validator.Validator, subscription *Subscription) {
+	v.Check(subscription.SeriesName != "", "series_name", "must be provided")
+	v.Check(len(subscription.SeriesName) <= 500, "series_name", "must not be more than 500 bytes long")
+	v.Check(subscription.SeriesName == "" || subscription.SeriesKey != "", "series_name", "must contain letters or digits")
+}
+
+type PullListModel struct {
+	DB *sql.DB
+}
+
+func (m PullListModel) Insert(subscription *Subscription) error {
+	query := `INSERT INTO pull_list (user_id, series_name, series_key)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{subscription.UserID, subscription.SeriesName, subscription.SeriesKey}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&subscription.ID, &subscription.CreatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "pull_list_user_series_idx"`:
+			return ErrDuplicateSubscription
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m PullListModel) GetAllForUser(userID int64) ([]*Subscription, error) {
+	query := `SELECT id, created_at, user_id, series_name, series_key
+			FROM pull_list
+			WHERE user_id = $1
+			ORDER BY series_key, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []*Subscription{}
+	for rows.Next() {
+		var subscription Subscription
+		err := rows.Scan(
+			&subscription.ID,
+			&subscription.CreatedAt,
+			&subscription.UserID,
+			&subscription.SeriesName,
+			&subscription.SeriesKey,
+		)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, &subscription)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Delete() removes a subscription from the pull list of a user. Holds already recorded
+// for it are kept.
+func (m PullListModel) Delete(userID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM pull_list
+			WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+const holdsQuery = `
+		SELECT pull_list_holds.id, pull_list_holds.created_at, pull_list_holds.user_id, pull_list_holds.comic_id,
+			pull_list_holds.subscription_id, comics.title, comics.issue_number, users.name, users.email
+		FROM pull_list_holds
+		INNER JOIN comics ON comics.id = pull_list_holds.comic_id
+		INNER JOIN users ON users.id = pull_list_holds.user_id`
+
+func (m PullListModel) getHolds(where string, args ...interface{}) ([]*Hold, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, holdsQuery+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []*Hold{}
+	for rows.Next() {
+		var hold Hold
+		err := rows.Scan(
+			&hold.ID,
+			&hold.CreatedAt,
+			&hold.UserID,
+			&hold.ComicsID,
+			&hold.SubscriptionID,
+			&hold.Title,
+			&hold.IssueNumber,
+			&hold.UserName,
+			&hold.UserEmail,
+		)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, &hold)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+// GetHoldsForUser() lists the issues held for a user, newest first.
+func (m PullListModel) GetHoldsForUser(userID int64) ([]*Hold, error) {
+	return m.getHolds(` WHERE pull_list_holds.user_id = $1 ORDER BY pull_list_holds.id DESC`, userID)
+}
+
+// GetHoldsForComics() lists the holds recorded for an issue, together with the name and
+// email address of the users they were recorded for.
+func (m PullListModel) GetHoldsForComics(comicsID int64) ([]*Hold, error) {
+	return m.getHolds(` WHERE pull_list_holds.comic_id = $1 ORDER BY pull_list_holds.id`, comicsID)
+}
+
+// DeleteHold() releases an issue held for a user.
+func (m PullListModel) DeleteHold(userID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM pull_list_holds
+			WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// The recordHolds() helper holds a newly created issue for every user whose pull list
+// contains its series. It runs inside the transaction that inserts the issue.
+func recordHolds(ctx context.Context, tx *sql.Tx, comics *Comics) error {
+	if comics.SeriesID == nil {
+		return nil
+	}
+
+	var name string
+	err := tx.QueryRowContext(ctx, `SELECT name FROM series WHERE id = $1`, *comics.SeriesID).Scan(&name)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO pull_list_holds (user_id, comic_id, subscription_id)
+			SELECT user_id, $1, id
+			FROM pull_list
+			WHERE series_key = $2
+			ON CONFLICT DO NOTHING`
+
+	_, err = tx.ExecContext(ctx, query, comics.ID, NormalizeSeriesName(name))
+	return err
+}
@@ -0,0 +1,208 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"github.com/miras210/finalGolang/internal/validator"
+	"time"
+)
+
+// The statuses of an issue for a reader.
+const (
+	ReadUnread  = "unread"
+	ReadReading = "reading"
+	ReadRead    = "read"
+)
+
+var ReadStatuses = []string{ReadUnread, ReadReading, ReadRead}
+
+// A ReadState records how far a user got with an issue: the page they stopped at while
+// reading it, or the date they finished it. Unread issues have no stored state.
+type ReadState struct {
+	ComicsID  int64     `json:"comics_id"`
+	Title     string    `json:"title,omitempty"`
+	Status    string    `json:"status"`
+	LastPage  int32     `json:"last_page,omitempty"`
+	ReadDate  *Date     `json:"read_date,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// A NextIssue is the issue of a series a user should read next, with how far they got
+// with it if they already started.
+type NextIssue struct {
+	SeriesID    int64       `json:"series_id"`
+	SeriesName  string      `json:"series_name"`
+	ComicsID    int64       `json:"comics_id"`
+	Title       string      `json:"title"`
+	IssueNumber IssueNumber `json:"issue_number,omitempty"`
+	Status      string      `json:"status"`
+	LastPage    int32       `json:"last_page,omitempty"`
+}
+
+// ValidateReadState() checks a read state against the page count of its issue. Read
+// states without a read date are completed with today's date.
+func ValidateReadState(v *validator.Validator, state *ReadState, pages Pages) {
+	v.Check(validator.In(state.Status, ReadStatuses...), "status", "must be unread, reading or read")
+
+	switch state.Status {
+	case ReadReading:
+		v.Check(state.LastPage > 0, "last_page", "must be provided while reading")
+		v.Check(state.LastPage <= int32(pages), "last_page", "must not be past the last page of the issue")
+		v.Check(state.ReadDate == nil, "read_date", "must only be set once the issue is read")
+	case ReadRead:
+		v.Check(state.LastPage == 0, "last_page", "must only be set while reading")
+		if state.ReadDate == nil {
+			today := Today()
+			state.ReadDate = &today
+		}
+		v.Check(!state.ReadDate.After(Today().Time), "read_date", "must not be in the future")
+	default:
+		v.Check(state.LastPage == 0, "last_page", "must only be set while reading")
+		v.Check(state.ReadDate == nil, "read_date", "must only be set once the issue is read")
+	}
+}
+
+type ReadStateModel struct {
+	DB *sql.DB
+}
+
+// SetAll() saves the read states of a user in a single transaction, so that a failed
+// bulk update leaves nothing half done. Marking an issue unread removes its state.
+func (m ReadStateModel) SetAll(userID int64, states []*ReadState) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, state := range states {
+		if state.Status == ReadUnread {
+			_, err = tx.ExecContext(ctx, `DELETE FROM read_states WHERE user_id = $1 AND comic_id = $2`, userID, state.ComicsID)
+			if err != nil {
+				return err
+			}
+			state.UpdatedAt = time.Now()
+			continue
+		}
+
+		query := `
+			INSERT INTO read_states (user_id, comic_id, status, last_page, read_date)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, comic_id)
+			DO UPDATE SET status = EXCLUDED.status, last_page = EXCLUDED.last_page, read_date = EXCLUDED.read_date,
+			              updated_at = NOW()
+			RETURNING updated_at`
+
+		args := []interface{}{userID, state.ComicsID, state.Status, state.LastPage, state.ReadDate}
+
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&state.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetAllForUser() lists the read states of a user, the most recently updated first. An
+// empty status lists issues being read and issues already read.
+func (m ReadStateModel) GetAllForUser(userID int64, status string) ([]*ReadState, error) {
+	query := `
+		SELECT read_states.comic_id, comics.title, read_states.status, read_states.last_page,
+		       read_states.read_date, read_states.updated_at
+		FROM read_states
+		INNER JOIN comics ON comics.id = read_states.comic_id
+		WHERE read_states.user_id = $1
+		AND (read_states.status = $2 OR $2 = '')
+		ORDER BY read_states.updated_at DESC, read_states.comic_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := []*ReadState{}
+	for rows.Next() {
+		var state ReadState
+		err := rows.Scan(&state.ComicsID, &state.Title, &state.Status, &state.LastPage, &state.ReadDate, &state.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, &state)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// ContinueReading() returns the next issue to read in every series the user follows,
+// that is every series in which they have started or read an issue. The next issue is
+// an issue they are in the middle of, even one before the last issue they read, or else
+// the first one after the last issue they read that they haven't read yet. Series they
+// are done with are left out.
+func (m ReadStateModel) ContinueReading(userID int64) ([]*NextIssue, error) {
+	query := `
+		WITH followed AS (
+			SELECT comics.series_id, max(comics.issue_sort) FILTER (WHERE read_states.status = 'read') AS last_read,
+			       max(read_states.updated_at) AS last_activity
+			FROM read_states
+			INNER JOIN comics ON comics.id = read_states.comic_id
+			WHERE read_states.user_id = $1 AND comics.series_id IS NOT NULL
+			GROUP BY comics.series_id
+		), next AS (
+			SELECT DISTINCT ON (followed.series_id)
+			       followed.series_id, series.name, comics.id, comics.title, comics.issue_number,
+			       COALESCE(read_states.status, 'unread') AS status, COALESCE(read_states.last_page, 0) AS last_page,
+			       followed.last_activity
+			FROM followed
+			INNER JOIN series ON series.id = followed.series_id
+			INNER JOIN comics ON comics.series_id = followed.series_id
+			LEFT JOIN read_states ON read_states.comic_id = comics.id AND read_states.user_id = $1
+			WHERE read_states.status = 'reading'
+			OR ((comics.issue_sort > followed.last_read OR followed.last_read IS NULL)
+			    AND read_states.status IS DISTINCT FROM 'read')
+			ORDER BY followed.series_id, read_states.status = 'reading' DESC NULLS LAST, comics.issue_sort, comics.id
+		)
+		SELECT series_id, name, id, title, issue_number, status, last_page
+		FROM next
+		ORDER BY last_activity DESC, series_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := []*NextIssue{}
+	for rows.Next() {
+		var issue NextIssue
+		err := rows.Scan(
+			&issue.SeriesID,
+			&issue.SeriesName,
+			&issue.ComicsID,
+			&issue.Title,
+			&issue.IssueNumber,
+			&issue.Status,
+			&issue.LastPage,
+		)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, &issue)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
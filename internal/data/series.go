@@ -0,0 +1,231 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/validator"
+	"strings"
+	"time"
+)
+
+// ErrUnknownSeries is returned when a comics issue references a series that doesn't exist.
+var ErrUnknownSeries = errors.New("unknown series")
+
+var SeriesStatuses = []string{"ongoing", "completed", "cancelled", "hiatus"}
+
+type Series struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	Publisher string    `json:"publisher,omitempty"`
+	Volume    int32     `json:"volume"`
+	StartYear int32     `json:"start_year"`
+	Status    string    `json:"status"`
+	Version   int32     `json:"version"`
+}
+
+func ValidateSeries(v *validator.Validator, series *Series) {
+	v.Check(strings.TrimSpace(series.Name) != "", "name", "must be provided")
+	v.Check(len(series.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	v.Check(len(series.Publisher) <= 500, "publisher", "must not be more than 500 bytes long")
+
+	v.Check(series.Volume > 0, "volume", "must be a positive integer")
+
+	v.Check(series.StartYear != 0, "start_year", "must be provided")
+	v.Check(series.StartYear >= 1888, "start_year", "must be greater than 1888")
+	v.Check(series.StartYear <= int32(time.Now().Year()), "start_year", "must not be in the future")
+
+	v.Check(validator.In(series.Status, SeriesStatuses...), "status", "must be one of ongoing, completed, cancelled or hiatus")
+}
+
+type SeriesModel struct {
+	DB *sql.DB
+}
+
+func (m SeriesModel) Insert(series *Series) error {
+	query := `INSERT INTO series (name, publisher, volume, start_year, status)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at, version`
+
+	args := []interface{}{series.Name, series.Publisher, series.Volume, series.StartYear, series.Status}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&series.ID, &series.CreatedAt, &series.Version)
+}
+
+func (m SeriesModel) Get(id int64) (*Series, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `SELECT id, created_at, name, publisher, volume, start_year, status, version
+			FROM series
+			WHERE id = $1`
+
+	var series Series
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&series.ID,
+		&series.CreatedAt,
+		&series.Name,
+		&series.Publisher,
+		&series.Volume,
+		&series.StartYear,
+		&series.Status,
+		&series.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &series, nil
+}
+
+// GetByName() finds a series by its name, ignoring case. If volume is zero the most
+// recent volume of the series is returned.
+func (m SeriesModel) GetByName(name string, volume int32) (*Series, error) {
+	query := `SELECT id, created_at, name, publisher, volume, start_year, status, version
+			FROM series
+			WHERE lower(name) = lower($1) AND (volume = $2 OR $2 = 0)
+			ORDER BY volume DESC, id ASC
+			LIMIT 1`
+
+	var series Series
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, name, volume).Scan(
+		&series.ID,
+		&series.CreatedAt,
+		&series.Name,
+		&series.Publisher,
+		&series.Volume,
+		&series.StartYear,
+		&series.Status,
+		&series.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &series, nil
+}
+
+func (m SeriesModel) Update(series *Series) error {
+	query := `UPDATE series
+			SET name = $1, publisher = $2, volume = $3, start_year = $4, status = $5, version = version + 1
+			WHERE id = $6 AND version = $7
+			RETURNING version`
+	args := []interface{}{
+		series.Name,
+		series.Publisher,
+		series.Volume,
+		series.StartYear,
+		series.Status,
+		series.ID,
+		series.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&series.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Deleting a series keeps its issues, but detaches them from the series (the foreign key
+// on comics.series_id is declared with ON DELETE SET NULL).
+func (m SeriesModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM series
+			WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (m SeriesModel) GetAll(name string, status string, filters Filters) ([]*Series, Metadata, error) {
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), id, created_at, name, publisher, volume, start_year, status, version
+		FROM series
+		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+		AND (status = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{name, status, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	series := []*Series{}
+	for rows.Next() {
+		var s Series
+		err := rows.Scan(
+			&totalRecords,
+			&s.ID,
+			&s.CreatedAt,
+			&s.Name,
+			&s.Publisher,
+			&s.Volume,
+			&s.StartYear,
+			&s.Status,
+			&s.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		series = append(series, &s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return series, metadata, nil
+}
@@ -0,0 +1,205 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A Shipment is a shipping list ready to be imported: the issues to create or update and
+// the copies to receive into the inventory at Location. ListHash identifies the list, so
+// that each of its lines is only received once.
+type Shipment struct {
+	ListHash []byte
+	Location string
+	UserID   *int64
+	Note     string
+	Lines    []*ShipmentLine
+}
+
+// A ShipmentLine is a single line of a shipping list. Issues without an ID are created,
+// in the series named by Series if it's set, and existing issues are saved when Update
+// is set. Publisher names the publisher the issue should belong to. Received is set once
+// the line's copies are in the inventory; AlreadyReceived when that happened in an
+// earlier import of the same list.
+type ShipmentLine struct {
+	Line            int
+	Comics          *Comics
+	Update          bool
+	Publisher       string
+	Series          string
+	Quantity        int32
+	AlreadyReceived bool
+}
+
+type ShipmentModel struct {
+	DB *sql.DB
+}
+
+// GetReceivedLines() returns the line numbers of a shipping list that were already
+// received.
+func (m ShipmentModel) GetReceivedLines(listHash []byte) (map[int]bool, error) {
+	query := `
+		SELECT line
+		FROM shipping_receipts
+		WHERE list_hash = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, listHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := make(map[int]bool)
+	for rows.Next() {
+		var line int
+		err := rows.Scan(&line)
+		if err != nil {
+			return nil, err
+		}
+		lines[line] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// Apply() imports a shipment in a single transaction, so that a failing line leaves the
+// catalogue and the inventory as they were. Publishers and series that aren't in the
+// catalogue yet are created. Lines already received from the same list are skipped, so
+// a retried upload doesn't receive its copies twice. Large lists take a while, hence the
+// longer timeout.
+func (m ShipmentModel) Apply(shipment *Shipment) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, line := range shipment.Lines {
+		err = applyShipmentLine(ctx, tx, shipment, line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line.Line, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func applyShipmentLine(ctx context.Context, tx *sql.Tx, shipment *Shipment, line *ShipmentLine) error {
+	comics := line.Comics
+
+	if line.Publisher != "" {
+		publisherID, err := findOrCreatePublisher(ctx, tx, line.Publisher)
+		if err != nil {
+			return err
+		}
+		if comics.PublisherID == nil || *comics.PublisherID != publisherID {
+			comics.ImprintID = nil
+		}
+		comics.PublisherID = &publisherID
+	}
+
+	switch {
+	case comics.ID == 0:
+		if line.Series != "" {
+			seriesID, err := findOrCreateSeries(ctx, tx, line.Series)
+			if err != nil {
+				return err
+			}
+			comics.SeriesID = &seriesID
+		}
+		err := insertComics(ctx, tx, comics)
+		if err != nil {
+			return err
+		}
+	case line.Update:
+		err := updateComics(ctx, tx, comics)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO shipping_receipts (list_hash, line, comic_id, location, quantity)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (list_hash, line) DO NOTHING`
+
+	result, err := tx.ExecContext(ctx, query, shipment.ListHash, line.Line, comics.ID, shipment.Location, line.Quantity)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		line.AlreadyReceived = true
+		return nil
+	}
+
+	query = `
+		INSERT INTO inventory (comic_id, location, on_hand)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (comic_id, (COALESCE(variant_id, 0)), location)
+		DO UPDATE SET on_hand = inventory.on_hand + EXCLUDED.on_hand, version = inventory.version + 1
+		RETURNING id`
+
+	var inventoryID int64
+	err = tx.QueryRowContext(ctx, query, comics.ID, shipment.Location, line.Quantity).Scan(&inventoryID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO stock_adjustments (inventory_id, user_id, delta, reason, note)
+		VALUES ($1, $2, $3, 'received', $4)`
+
+	note := strings.TrimSpace(fmt.Sprintf("%s line %d", shipment.Note, line.Line))
+	_, err = tx.ExecContext(ctx, query, inventoryID, shipment.UserID, line.Quantity, note)
+	return err
+}
+
+func findOrCreatePublisher(ctx context.Context, tx *sql.Tx, name string) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM publishers WHERE name = $1`, name).Scan(&id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return id, err
+	}
+	err = tx.QueryRowContext(ctx, `INSERT INTO publishers (name) VALUES ($1) RETURNING id`, name).Scan(&id)
+	return id, err
+}
+
+// findOrCreateSeries() returns the series with the given name, preferring its latest
+// volume, and creates a first volume starting this year if there is none.
+func findOrCreateSeries(ctx context.Context, tx *sql.Tx, name string) (int64, error) {
+	query := `
+		SELECT id
+		FROM series
+		WHERE lower(name) = lower($1)
+		ORDER BY volume DESC, id ASC
+		LIMIT 1`
+
+	var id int64
+	err := tx.QueryRowContext(ctx, query, name).Scan(&id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return id, err
+	}
+
+	query = `
+		INSERT INTO series (name, publisher, volume, start_year, status)
+		VALUES ($1, '', 1, $2, 'ongoing')
+		RETURNING id`
+	err = tx.QueryRowContext(ctx, query, name, time.Now().Year()).Scan(&id)
+	return id, err
+}
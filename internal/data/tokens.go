@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base32"
+	"errors"
 	"github.com/miras210/finalGolang/internal/validator"
 	"time"
 )
@@ -13,8 +14,15 @@ import (
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopePasswordReset  = "password-reset"
+	ScopeEmailChange    = "email-change"
+	ScopeRefresh        = "refresh"
 )
 
+// ErrRefreshTokenReused is returned when a refresh token that was already rotated is
+// presented again, which means it was most likely stolen.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
 // Define a Token struct to hold the data for an individual token. This includes the
 // plaintext and hashed versions of the token, associated user ID, expiry time and
 // scope.
@@ -24,6 +32,21 @@ type Token struct {
 	UserID    int64     `json:"-"`
 	Expiry    time.Time `json:"expiry"`
 	Scope     string    `json:"-"`
+	UserAgent string    `json:"-"`
+	IP        string    `json:"-"`
+	Family    int64     `json:"-"`
+}
+
+// A Session describes an authentication token without giving the token away, so that
+// users can see where they are signed in and revoke tokens they don't recognise.
+type Session struct {
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Expiry     time.Time  `json:"expiry"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	Current    bool       `json:"current"`
 }
 
 func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
@@ -65,12 +88,134 @@ func (m TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token,
 	return token, err
 }
 
+// NewSession() signs a user in by creating an authentication token and a refresh token
+// that start a new token family, recording the client they were issued to.
+func (m TokenModel) NewSession(userID int64, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*Token, *Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var family int64
+	err = tx.QueryRowContext(ctx, `SELECT nextval('token_families_seq')`).Scan(&family)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	access, refresh, err := insertTokenPair(ctx, tx, userID, family, accessTTL, refreshTTL, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	return access, refresh, tx.Commit()
+}
+
+// Rotate() exchanges a refresh token for a new authentication token and refresh token
+// in the same family. The authentication tokens issued earlier in the family are revoked
+// and the old refresh token is marked as used. Presenting a used refresh token revokes
+// every token of its family and returns ErrRefreshTokenReused, so that whoever holds a
+// stolen token is signed out along with the legitimate client.
+func (m TokenModel) Rotate(refreshPlaintext string, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*Token, *Token, error) {
+	refreshHash := sha256.Sum256([]byte(refreshPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT user_id, family, used_at
+		FROM tokens
+		WHERE hash = $1 AND scope = $2 AND expiry > NOW()
+		FOR UPDATE`
+
+	var (
+		userID int64
+		family int64
+		usedAt *time.Time
+	)
+	err = tx.QueryRowContext(ctx, query, refreshHash[:], ScopeRefresh).Scan(&userID, &family, &usedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	if usedAt != nil {
+		_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE family = $1`, family)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = tx.Commit()
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE tokens SET used_at = NOW() WHERE hash = $1`, refreshHash[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE family = $1 AND scope = $2`, family, ScopeAuthentication)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	access, refresh, err := insertTokenPair(ctx, tx, userID, family, accessTTL, refreshTTL, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	return access, refresh, tx.Commit()
+}
+
+// insertTokenPair() generates and stores an authentication token and a refresh token in
+// the given family.
+func insertTokenPair(ctx context.Context, tx *sql.Tx, userID, family int64, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*Token, *Token, error) {
+	query := `
+		INSERT INTO tokens (hash, user_id, expiry, scope, user_agent, ip, family)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	access, err := generateToken(userID, accessTTL, ScopeAuthentication)
+	if err != nil {
+		return nil, nil, err
+	}
+	refresh, err := generateToken(userID, refreshTTL, ScopeRefresh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, token := range []*Token{access, refresh} {
+		token.UserAgent = userAgent
+		token.IP = ip
+		token.Family = family
+
+		args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope, token.UserAgent, token.IP, token.Family}
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return access, refresh, nil
+}
+
 // Insert() adds the data for a specific token to the tokens table.
 func (m TokenModel) Insert(token *Token) error {
 	query := `
-		INSERT INTO tokens (hash, user_id, expiry, scope)
-		VALUES ($1, $2, $3, $4)`
-	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope}
+		INSERT INTO tokens (hash, user_id, expiry, scope, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope, token.UserAgent, token.IP}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	_, err := m.DB.ExecContext(ctx, query, args...)
@@ -87,3 +232,116 @@ func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
 	_, err := m.DB.ExecContext(ctx, query, scope, userID)
 	return err
 }
+
+// Delete() deletes a single token of the given scope. Deleting a token that belongs to a
+// token family revokes the rest of the family too, so that signing out also invalidates
+// the refresh token issued with the authentication token.
+func (m TokenModel) Delete(scope, tokenPlaintext string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+	query := `
+		DELETE FROM tokens
+		WHERE scope = $1 AND hash = $2
+		RETURNING family`
+	return m.deleteWithFamily(query, scope, tokenHash[:])
+}
+
+// Touch() records that an authentication token was just used. To spare the database a
+// write on every request, the time is only moved forward once a minute.
+func (m TokenModel) Touch(tokenPlaintext string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+	query := `
+		UPDATE tokens
+		SET last_used_at = NOW()
+		WHERE hash = $1
+		AND (last_used_at IS NULL OR last_used_at < NOW() - interval '1 minute')`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, tokenHash[:])
+	return err
+}
+
+// GetSessions() lists the unexpired authentication tokens of a user, the most recently
+// used first. The session of currentPlaintext, if any, is flagged as the current one.
+func (m TokenModel) GetSessions(userID int64, currentPlaintext string) ([]*Session, error) {
+	currentHash := sha256.Sum256([]byte(currentPlaintext))
+	query := `
+		SELECT id, created_at, last_used_at, expiry, user_agent, ip, hash = $3
+		FROM tokens
+		WHERE scope = $1 AND user_id = $2 AND expiry > NOW()
+		ORDER BY COALESCE(last_used_at, created_at) DESC, id DESC`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, ScopeAuthentication, userID, currentHash[:])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(
+			&session.ID,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.Expiry,
+			&session.UserAgent,
+			&session.IP,
+			&session.Current,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeleteSession() revokes one authentication token of a user by its session id, along
+// with the rest of its token family.
+func (m TokenModel) DeleteSession(userID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `
+		DELETE FROM tokens
+		WHERE id = $1 AND user_id = $2 AND scope = $3
+		RETURNING family`
+	return m.deleteWithFamily(query, id, userID, ScopeAuthentication)
+}
+
+// deleteWithFamily() runs a query deleting a single token and returning its family, then
+// deletes the remaining tokens of that family. It returns ErrRecordNotFound if the query
+// deleted nothing.
+func (m TokenModel) deleteWithFamily(query string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var family *int64
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&family)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if family != nil {
+		_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE family = $1`, *family)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
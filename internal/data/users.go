@@ -222,3 +222,47 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	}
 	return &user, nil
 }
+
+// SetPendingEmail() records the address a user wants to change their email to until they
+// confirm it. Asking again replaces the previous pending address.
+func (m UserModel) SetPendingEmail(userID int64, email string) error {
+	query := `
+		INSERT INTO email_changes (user_id, email)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id)
+		DO UPDATE SET email = EXCLUDED.email, created_at = NOW()`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, userID, email)
+	return err
+}
+
+func (m UserModel) GetPendingEmail(userID int64) (string, error) {
+	query := `
+		SELECT email
+		FROM email_changes
+		WHERE user_id = $1`
+	var email string
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&email)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+	return email, nil
+}
+
+func (m UserModel) DeletePendingEmail(userID int64) error {
+	query := `
+		DELETE FROM email_changes
+		WHERE user_id = $1`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,249 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/miras210/finalGolang/internal/validator"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ErrDuplicateSKU = errors.New("duplicate sku")
+
+var (
+	RatioRX = regexp.MustCompile(`^1:[1-9][0-9]*$`)
+	SkuRX   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// A Variant is one of the covers an issue is sold with (Cover A, a 1:25 ratio variant, a
+// foil edition and so on). Variants share the story content of their issue, but have
+// their own SKU and price.
+type Variant struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"-"`
+	ComicsID   int64     `json:"comics_id"`
+	CoverLabel string    `json:"cover_label"`
+	Ratio      string    `json:"ratio,omitempty"`
+	Artist     string    `json:"artist,omitempty"`
+	SKU        string    `json:"sku"`
+	Price      Price     `json:"price"`
+	Version    int32     `json:"version"`
+}
+
+// ValidateVariant() checks a variant on its own. The comics handlers validate the variants
+// of an issue with ValidateComics() instead, which reports them as "variants[i].field".
+func ValidateVariant(v *validator.Validator, variant *Variant) {
+	validateVariant(v, variant, "")
+}
+
+// The validateVariant() helper checks a variant, prefixing the key of every error with
+// prefix.
+func validateVariant(v *validator.Validator, variant *Variant, prefix string) {
+	v.Check(strings.TrimSpace(variant.CoverLabel) != "", prefix+"cover_label", "must be provided")
+	v.Check(len(variant.CoverLabel) <= 100, prefix+"cover_label", "must not be more than 100 bytes long")
+
+	v.Check(variant.Ratio == "" || validator.Matches(variant.Ratio, RatioRX), prefix+"ratio", "must be in the form 1:N")
+
+	v.Check(len(variant.Artist) <= 500, prefix+"artist", "must not be more than 500 bytes long")
+
+	v.Check(variant.SKU != "", prefix+"sku", "must be provided")
+	v.Check(len(variant.SKU) <= 50, prefix+"sku", "must not be more than 50 bytes long")
+	v.Check(validator.Matches(variant.SKU, SkuRX), prefix+"sku", "must only contain letters, digits, dots, dashes and underscores")
+
+	v.Check(variant.Price >= 0, prefix+"price", "must not be negative")
+}
+
+type VariantModel struct {
+	DB *sql.DB
+}
+
+// The insertVariant() helper inserts a variant using the given transaction, so that
+// variants can be created together with their issue in ComicsModel.Insert().
+func insertVariant(ctx context.Context, tx *sql.Tx, variant *Variant) error {
+	query := `INSERT INTO comic_variants (comic_id, cover_label, ratio, artist, sku, price)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, created_at, version`
+
+	args := []interface{}{
+		variant.ComicsID,
+		variant.CoverLabel,
+		variant.Ratio,
+		variant.Artist,
+		variant.SKU,
+		variant.Price,
+	}
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&variant.ID, &variant.CreatedAt, &variant.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "comic_variants_sku_key"`:
+			return ErrDuplicateSKU
+		default:
+			return err
+		}
+	}
+	return recordPrice(ctx, tx, variant.ComicsID, &variant.ID, variant.Price)
+}
+
+func (m VariantModel) Insert(variant *Variant) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertVariant(ctx, tx, variant)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m VariantModel) Get(id int64) (*Variant, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `SELECT id, created_at, comic_id, cover_label, ratio, artist, sku, price, version
+			FROM comic_variants
+			WHERE id = $1`
+
+	var variant Variant
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&variant.ID,
+		&variant.CreatedAt,
+		&variant.ComicsID,
+		&variant.CoverLabel,
+		&variant.Ratio,
+		&variant.Artist,
+		&variant.SKU,
+		&variant.Price,
+		&variant.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &variant, nil
+}
+
+// GetAllForComics() returns all variants of a single issue, ordered by cover label.
+func (m VariantModel) GetAllForComics(comicsID int64) ([]*Variant, error) {
+	query := `SELECT id, created_at, comic_id, cover_label, ratio, artist, sku, price, version
+			FROM comic_variants
+			WHERE comic_id = $1
+			ORDER BY cover_label, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, comicsID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []*Variant{}
+	for rows.Next() {
+		var variant Variant
+		err := rows.Scan(
+			&variant.ID,
+			&variant.CreatedAt,
+			&variant.ComicsID,
+			&variant.CoverLabel,
+			&variant.Ratio,
+			&variant.Artist,
+			&variant.SKU,
+			&variant.Price,
+			&variant.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, &variant)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+func (m VariantModel) Update(variant *Variant) error {
+	query := `UPDATE comic_variants
+			SET cover_label = $1, ratio = $2, artist = $3, sku = $4, price = $5, version = version + 1
+			WHERE id = $6 AND version = $7
+			RETURNING version`
+	args := []interface{}{
+		variant.CoverLabel,
+		variant.Ratio,
+		variant.Artist,
+		variant.SKU,
+		variant.Price,
+		variant.ID,
+		variant.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&variant.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "comic_variants_sku_key"`:
+			return ErrDuplicateSKU
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = recordPrice(ctx, tx, variant.ComicsID, &variant.ID, variant.Price)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m VariantModel) Delete(comicsID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM comic_variants
+			WHERE id = $1 AND comic_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, comicsID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
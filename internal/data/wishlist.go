@@ -0,0 +1,212 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrDuplicateWishlistItem = errors.New("duplicate wishlist item")
+
+// A WishlistItem is an issue a user wants to be told about when it's available to buy.
+// The title and stock are read from the issue whenever an item is loaded.
+type WishlistItem struct {
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UserID     int64      `json:"-"`
+	ComicsID   int64      `json:"comics_id"`
+	Title      string     `json:"title"`
+	Stock      int32      `json:"stock"`
+	Available  bool       `json:"available"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+	UserName   string     `json:"-"`
+	UserEmail  string     `json:"-"`
+}
+
+// wishlistAvailable reports whether the issue of a wishlist item has any unreserved
+// copies, the same way as the in_stock filter of ComicsModel.GetAll().
+const wishlistAvailable = `EXISTS (SELECT 1 FROM inventory WHERE inventory.comic_id = wishlist.comic_id AND on_hand > reserved)`
+
+type WishlistModel struct {
+	DB *sql.DB
+}
+
+// Insert() adds an issue to the wishlist of a user. The current availability of the
+// issue is recorded with it, so that an issue which is already in stock isn't announced
+// as being back in stock.
+func (m WishlistModel) Insert(item *WishlistItem) error {
+	query := `INSERT INTO wishlist (user_id, comic_id, was_available)
+			VALUES ($1, $2, EXISTS (SELECT 1 FROM inventory WHERE comic_id = $2 AND on_hand > reserved))
+			RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, item.UserID, item.ComicsID).Scan(&item.ID, &item.CreatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "wishlist_user_comic_idx"`:
+			return ErrDuplicateWishlistItem
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAllForUser() lists the wishlist of a user, the most recently added issues first.
+func (m WishlistModel) GetAllForUser(userID int64) ([]*WishlistItem, error) {
+	query := fmt.Sprintf(`
+		SELECT wishlist.id, wishlist.created_at, wishlist.user_id, wishlist.comic_id, comics.title,
+		       %s, wishlist.notified_at
+		FROM wishlist
+		INNER JOIN comics ON comics.id = wishlist.comic_id
+		WHERE wishlist.user_id = $1
+		ORDER BY wishlist.id DESC`, comicsStockColumn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*WishlistItem{}
+	for rows.Next() {
+		var item WishlistItem
+		err := rows.Scan(
+			&item.ID,
+			&item.CreatedAt,
+			&item.UserID,
+			&item.ComicsID,
+			&item.Title,
+			&item.Stock,
+			&item.NotifiedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.Available = item.Stock > 0
+		items = append(items, &item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (m WishlistModel) Delete(userID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `DELETE FROM wishlist
+			WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Restocked() finds the wishlist items whose issue went from unavailable to available
+// since the last call, records the new availability of every item, and returns the items
+// whose users should be told and haven't been yet. To keep stock that keeps selling out
+// and coming back from flooding anyone's inbox, a user notified about an issue within the
+// cooldown isn't notified about it again; such items are still recorded as available.
+// Items stay pending until MarkNotified() is called for them, so a failed email is retried
+// on the next call while the issue is still available.
+func (m WishlistModel) Restocked(cooldown time.Duration) ([]*WishlistItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE wishlist SET was_available = false WHERE was_available AND NOT `+wishlistAvailable)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE wishlist
+		SET was_available = true,
+		    notify_pending = notify_pending OR notified_at IS NULL OR notified_at <= NOW() - $1::float8 * interval '1 second'
+		WHERE NOT was_available AND %s`, wishlistAvailable)
+
+	_, err = tx.ExecContext(ctx, query, cooldown.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf(`
+		SELECT wishlist.id, wishlist.created_at, wishlist.user_id, wishlist.comic_id, comics.title,
+		       wishlist.notified_at, users.name, users.email
+		FROM wishlist
+		INNER JOIN comics ON comics.id = wishlist.comic_id
+		INNER JOIN users ON users.id = wishlist.user_id
+		WHERE wishlist.notify_pending AND %s
+		ORDER BY wishlist.id`, wishlistAvailable)
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*WishlistItem{}
+	for rows.Next() {
+		var item WishlistItem
+		err := rows.Scan(
+			&item.ID,
+			&item.CreatedAt,
+			&item.UserID,
+			&item.ComicsID,
+			&item.Title,
+			&item.NotifiedAt,
+			&item.UserName,
+			&item.UserEmail,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.Available = true
+		items = append(items, &item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, tx.Commit()
+}
+
+// MarkNotified() records that the user of a wishlist item was told the issue is back in
+// stock.
+func (m WishlistModel) MarkNotified(item *WishlistItem) error {
+	query := `UPDATE wishlist
+			SET notified_at = NOW(), notify_pending = false
+			WHERE id = $1
+			RETURNING notified_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, item.ID).Scan(&item.NotifiedAt)
+}
@@ -0,0 +1,135 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of iCalendar documents (RFC 5545).
+const ContentType = "text/calendar; charset=utf-8"
+
+// A Calendar is an iCalendar document holding a list of all-day events. Calendar apps
+// that subscribe to its URL show the events and fetch it again from time to time.
+type Calendar struct {
+	ProdID string
+	Name   string
+	// RefreshInterval suggests how often subscribers should fetch the calendar again.
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// An Event is an all-day event. Its UID must stay the same whenever the calendar is
+// generated, so that calendar apps update events instead of duplicating them.
+type Event struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	URL         string
+	Updated     time.Time
+}
+
+// WriteTo() writes the calendar in the iCalendar format, folding long lines and escaping
+// text values as the format requires.
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	cw := &writer{w: bufio.NewWriter(w)}
+
+	cw.line("BEGIN", "VCALENDAR")
+	cw.line("VERSION", "2.0")
+	cw.line("PRODID", c.ProdID)
+	cw.line("CALSCALE", "GREGORIAN")
+	cw.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		cw.line("X-WR-CALNAME", escape(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		cw.line("REFRESH-INTERVAL;VALUE=DURATION", duration(c.RefreshInterval))
+		cw.line("X-PUBLISHED-TTL", duration(c.RefreshInterval))
+	}
+
+	for _, event := range c.Events {
+		cw.line("BEGIN", "VEVENT")
+		cw.line("UID", event.UID)
+		cw.line("DTSTAMP", event.Updated.UTC().Format("20060102T150405Z"))
+		cw.line("DTSTART;VALUE=DATE", event.Date.Format("20060102"))
+		cw.line("DTEND;VALUE=DATE", event.Date.AddDate(0, 0, 1).Format("20060102"))
+		cw.line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			cw.line("DESCRIPTION", escape(event.Description))
+		}
+		if event.URL != "" {
+			cw.line("URL", event.URL)
+		}
+		cw.line("TRANSP", "TRANSPARENT")
+		cw.line("END", "VEVENT")
+	}
+
+	cw.line("END", "VCALENDAR")
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// writer writes content lines, remembering the first error so that WriteTo() doesn't
+// have to check every line.
+type writer struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+// line() writes a content line, folding it so that no line is longer than 75 octets.
+// Lines are only folded between UTF-8 sequences, never in the middle of a character.
+func (cw *writer) line(name, value string) {
+	if cw.err != nil {
+		return
+	}
+
+	s := name + ":" + value
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		cw.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// The leading space of a continuation line counts towards its length.
+		limit = 74
+	}
+	cw.write(s + "\r\n")
+}
+
+func (cw *writer) write(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escape() escapes a TEXT property value.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// duration() formats a duration as an iCalendar DURATION value, in whole minutes.
+func duration(d time.Duration) string {
+	minutes := int64(d / time.Minute)
+	switch {
+	case minutes%(24*60) == 0:
+		return "P" + strconv.FormatInt(minutes/(24*60), 10) + "D"
+	case minutes%60 == 0:
+		return "PT" + strconv.FormatInt(minutes/60, 10) + "H"
+	default:
+		return "PT" + strconv.FormatInt(minutes, 10) + "M"
+	}
+}
@@ -0,0 +1,99 @@
+package opds
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Media types used by OPDS 1.2 catalogs.
+const (
+	NavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	OpenSearchType  = "application/opensearchdescription+xml"
+	CBZType         = "application/vnd.comicbook+zip"
+)
+
+// Link relations defined by Atom, OpenSearch and the OPDS specification.
+const (
+	RelSelf        = "self"
+	RelStart       = "start"
+	RelUp          = "up"
+	RelNext        = "next"
+	RelPrevious    = "previous"
+	RelFirst       = "first"
+	RelLast        = "last"
+	RelSearch      = "search"
+	RelSubsection  = "subsection"
+	RelAcquisition = "http://opds-spec.org/acquisition"
+	RelImage       = "http://opds-spec.org/image"
+	RelThumbnail   = "http://opds-spec.org/image/thumbnail"
+)
+
+// A Feed is an Atom feed document. Navigation feeds list links to other feeds, while
+// acquisition feeds list publications together with the links to download them.
+type Feed struct {
+	XMLName      xml.Name  `xml:"http://www.w3.org/2005/Atom feed"`
+	XMLNSOpds    string    `xml:"xmlns:opds,attr"`
+	XMLNSSearch  string    `xml:"xmlns:opensearch,attr"`
+	ID           string    `xml:"id"`
+	Title        string    `xml:"title"`
+	Updated      time.Time `xml:"updated"`
+	Author       *Author   `xml:"author,omitempty"`
+	TotalResults int       `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage int       `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   int       `xml:"opensearch:startIndex,omitempty"`
+	Links        []Link    `xml:"link"`
+	Entries      []Entry   `xml:"entry"`
+}
+
+// NewFeed() returns an empty feed with the namespace declarations filled in.
+func NewFeed(id, title string, updated time.Time) *Feed {
+	return &Feed{
+		XMLNSOpds:   "http://opds-spec.org/2010/catalog",
+		XMLNSSearch: "http://a9.com/-/spec/opensearch/1.1/",
+		ID:          id,
+		Title:       title,
+		Updated:     updated.UTC(),
+	}
+}
+
+type Author struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type Link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type Entry struct {
+	ID      string    `xml:"id"`
+	Title   string    `xml:"title"`
+	Updated time.Time `xml:"updated"`
+	Authors []Author  `xml:"author"`
+	Content *Content  `xml:"content,omitempty"`
+	Links   []Link    `xml:"link"`
+}
+
+type Content struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// An OpenSearchDescription tells clients how to build search URLs for the catalog. The
+// URL template must contain the {searchTerms} placeholder.
+type OpenSearchDescription struct {
+	XMLName       xml.Name        `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName     string          `xml:"ShortName"`
+	Description   string          `xml:"Description"`
+	InputEncoding string          `xml:"InputEncoding"`
+	URLs          []OpenSearchURL `xml:"Url"`
+}
+
+type OpenSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
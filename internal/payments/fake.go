@@ -0,0 +1,173 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Card numbers with a fixed outcome at the fake gateway. Any other card number that
+// passes the Luhn check is approved.
+const (
+	FakeCardApproved          = "4242424242424242"
+	FakeCardDeclined          = "4000000000000002"
+	FakeCardInsufficientFunds = "4000000000009995"
+)
+
+// Fake is an in-process Provider that never touches the network. It is meant for
+// development and testing: outcomes only depend on the card number, and webhooks are
+// signed with an HMAC-SHA256 of the request body using a shared secret.
+type Fake struct {
+	secret string
+	// prefix keeps references unique across restarts of the process, since the fake
+	// gateway forgets its payments when it stops.
+	prefix string
+
+	mu       sync.Mutex
+	next     int
+	payments map[string]*fakePayment
+}
+
+type fakePayment struct {
+	amount   int64
+	status   string
+	refunded int64
+}
+
+func NewFake(secret string) *Fake {
+	return &Fake{
+		secret:   secret,
+		prefix:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		payments: make(map[string]*fakePayment),
+	}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Authorize(amount int64, currency string, card Card) (Result, error) {
+	number := strings.ReplaceAll(card.Number, " ", "")
+	if !luhnValid(number) || card.ExpMonth < 1 || card.ExpMonth > 12 {
+		return Result{}, ErrInvalidCard
+	}
+	now := time.Now()
+	if card.ExpYear < now.Year() || (card.ExpYear == now.Year() && card.ExpMonth < int(now.Month())) {
+		return Result{}, ErrInvalidCard
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.next++
+	result := Result{
+		Reference: fmt.Sprintf("fake_%s_%06d", f.prefix, f.next),
+		Status:    StatusAuthorized,
+	}
+
+	switch number {
+	case FakeCardDeclined:
+		result.Status = StatusDeclined
+		result.DeclineCode = "card_declined"
+	case FakeCardInsufficientFunds:
+		result.Status = StatusDeclined
+		result.DeclineCode = "insufficient_funds"
+	}
+
+	f.payments[result.Reference] = &fakePayment{amount: amount, status: result.Status}
+	return result, nil
+}
+
+func (f *Fake) Capture(reference string, amount int64) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[reference]
+	if !ok {
+		return Result{}, ErrUnknownReference
+	}
+	if p.status != StatusAuthorized || amount > p.amount {
+		return Result{}, ErrInvalidState
+	}
+
+	p.amount = amount
+	p.status = StatusCaptured
+	return Result{Reference: reference, Status: p.status}, nil
+}
+
+// Refund() refunds part or all of a captured payment. The payment is only reported as
+// refunded once the whole amount has been returned.
+func (f *Fake) Refund(reference string, amount int64) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.payments[reference]
+	if !ok {
+		return Result{}, ErrUnknownReference
+	}
+	if p.status != StatusCaptured || amount > p.amount-p.refunded {
+		return Result{}, ErrInvalidState
+	}
+
+	p.refunded += amount
+	if p.refunded == p.amount {
+		p.status = StatusRefunded
+	}
+	return Result{Reference: reference, Status: p.status}, nil
+}
+
+// Sign() returns the signature the fake gateway sends with a webhook request body.
+func (f *Fake) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(f.secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *Fake) VerifyWebhook(payload []byte, signature string) (*Event, error) {
+	if !hmac.Equal([]byte(f.Sign(payload)), []byte(signature)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	err := json.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, err
+	}
+	if event.Reference == "" {
+		return nil, ErrUnknownReference
+	}
+	return &event, nil
+}
+
+// luhnValid() reports whether a card number consists of 12 to 19 digits and passes the
+// Luhn checksum.
+func luhnValid(number string) bool {
+	if len(number) < 12 || len(number) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
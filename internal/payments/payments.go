@@ -0,0 +1,88 @@
+package payments
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidCard      = errors.New("invalid card details")
+	ErrUnknownReference = errors.New("unknown payment reference")
+	ErrInvalidState     = errors.New("payment is not in a state that allows this operation")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// The statuses a payment can be in at the provider. A payment is pending while the
+// provider is being asked to authorize it.
+const (
+	StatusPending    = "pending"
+	StatusAuthorized = "authorized"
+	StatusCaptured   = "captured"
+	StatusDeclined   = "declined"
+	StatusRefunded   = "refunded"
+	StatusFailed     = "failed"
+)
+
+// transitions lists the statuses a payment may move to from each status. Declined,
+// failed and refunded payments are final.
+var transitions = map[string][]string{
+	StatusAuthorized: {StatusCaptured, StatusFailed},
+	StatusCaptured:   {StatusRefunded},
+}
+
+// CanTransition() reports whether a payment may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Card holds the card details a customer pays with. They are passed straight to the
+// provider and never stored.
+type Card struct {
+	Number   string
+	ExpMonth int
+	ExpYear  int
+	CVC      string
+}
+
+// Last4() returns the last four digits of the card number, which are safe to store and
+// show to the customer.
+func (c Card) Last4() string {
+	if len(c.Number) < 4 {
+		return c.Number
+	}
+	return c.Number[len(c.Number)-4:]
+}
+
+// A Result describes the outcome of a call to the provider. A declined payment is not an
+// error: the call succeeds with StatusDeclined and a provider specific decline code.
+type Result struct {
+	Reference   string
+	Status      string
+	DeclineCode string
+}
+
+// An Event is a payment status change reported by the provider through a webhook.
+type Event struct {
+	Type      string    `json:"type"`
+	Reference string    `json:"reference"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Provider is implemented by every payment gateway the shop can take money through.
+// Amounts are always in the smallest currency unit (cents).
+type Provider interface {
+	// Name() returns the identifier stored with each payment made through the provider.
+	Name() string
+	Authorize(amount int64, currency string, card Card) (Result, error)
+	Capture(reference string, amount int64) (Result, error)
+	Refund(reference string, amount int64) (Result, error)
+	// VerifyWebhook() checks the signature of a webhook request body and decodes the
+	// event it carries.
+	VerifyWebhook(payload []byte, signature string) (*Event, error)
+}
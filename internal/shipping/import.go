@@ -0,0 +1,295 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+	"github.com/miras210/finalGolang/internal/data"
+	"github.com/miras210/finalGolang/internal/validator"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPages is the page count given to issues first seen on a shipping list, since
+// the list doesn't say how long an issue is. It's the length of a standard monthly issue.
+const DefaultPages = 32
+
+// The actions an import takes for a row.
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionInvalid   = "invalid"
+)
+
+// A Change is the old and new value of a comics field changed by a row.
+type Change struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// A Result describes what an import does, or would do in a dry run, with a single row.
+// Rows that fail validation have their validator errors in Errors. AlreadyReceived is set
+// for rows whose copies an earlier import of the same list received already.
+type Result struct {
+	Line            int               `json:"line"`
+	ItemCode        string            `json:"item_code"`
+	Action          string            `json:"action"`
+	ComicsID        int64             `json:"comics_id,omitempty"`
+	Changes         map[string]Change `json:"changes,omitempty"`
+	Received        int32             `json:"received,omitempty"`
+	AlreadyReceived bool              `json:"already_received,omitempty"`
+	Errors          map[string]string `json:"errors,omitempty"`
+
+	row       *Row
+	comics    *data.Comics
+	publisher string
+	quantity  int32
+}
+
+type Report struct {
+	DryRun    bool      `json:"dry_run"`
+	Location  string    `json:"location"`
+	Created   int       `json:"created"`
+	Updated   int       `json:"updated"`
+	Unchanged int       `json:"unchanged"`
+	Invalid   int       `json:"invalid"`
+	Received  int       `json:"received"`
+	Rows      []*Result `json:"rows"`
+}
+
+// Valid() reports whether every row of the list passed validation.
+func (r *Report) Valid() bool {
+	return r.Invalid == 0
+}
+
+// An Importer upserts the issues on a shipping list and receives the shipped copies into
+// the inventory at Location. UserID and Note are recorded with the stock adjustments.
+// ListHash identifies the list, normally by the SHA-256 hash of the file, so that its
+// copies are only received once however often it is imported.
+type Importer struct {
+	Models   data.Models
+	Location string
+	UserID   *int64
+	Note     string
+	ListHash []byte
+}
+
+// Plan() validates the rows of a shipping list and works out what importing them would
+// change, without writing anything. Issues are matched by item code first, then by UPC.
+func (im *Importer) Plan(rows []*Row) (*Report, error) {
+	report := &Report{DryRun: true, Location: im.Location, Rows: []*Result{}}
+
+	itemCodes := make(map[string]int)
+	upcs := make(map[string]int)
+	publishers := make(map[int64]string)
+
+	received, err := im.Models.Shipments.GetReceivedLines(im.ListHash)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result := &Result{Line: row.Line, ItemCode: row.ItemCode, Changes: map[string]Change{}, row: row}
+		v := validator.New()
+
+		v.Check(row.ItemCode != "", "item_code", "must be provided")
+		if line, ok := itemCodes[row.ItemCode]; ok && row.ItemCode != "" {
+			v.AddError("item_code", fmt.Sprintf("must not appear more than once (see line %d)", line))
+		}
+		itemCodes[row.ItemCode] = row.Line
+
+		quantity, err := strconv.Atoi(row.Quantity)
+		if err != nil {
+			v.AddError("quantity", "must be an integer value")
+		} else {
+			data.ValidateQuantity(v, int32(quantity))
+		}
+
+		var price data.Price
+		if row.Price != "" {
+			price, err = data.ParsePrice(strings.TrimPrefix(row.Price, "$"))
+			if err != nil {
+				v.AddError("price", "must be a decimal amount such as 3.99")
+			}
+		}
+
+		upc := strings.NewReplacer(" ", "", "-", "").Replace(row.UPC)
+		if line, ok := upcs[upc]; ok && upc != "" {
+			v.AddError("upc", fmt.Sprintf("must not appear more than once (see line %d)", line))
+		}
+		upcs[upc] = row.Line
+
+		comics := &data.Comics{Year: int32(time.Now().Year()), Pages: DefaultPages}
+		existing, err := im.Models.Comics.GetByItemCode(row.ItemCode, upc)
+		switch {
+		case err == nil:
+			copied := *existing
+			comics = &copied
+			result.ComicsID = existing.ID
+		case !errors.Is(err, data.ErrRecordNotFound):
+			return nil, err
+		default:
+			existing = nil
+		}
+
+		// The issue number is only kept in issue_number; everything that shows an issue
+		// adds it to the title itself.
+		issue := strings.TrimPrefix(row.Issue, "#")
+
+		// Distributors tend to list titles in capitals, so a title that only differs in
+		// case is left alone.
+		if !strings.EqualFold(comics.Title, row.Title) {
+			setField(result, "title", comics.Title, row.Title)
+			comics.Title = row.Title
+		}
+		if issue != "" {
+			setField(result, "issue_number", string(comics.IssueNumber), issue)
+			comics.IssueNumber = data.IssueNumber(issue)
+		}
+		if row.Price != "" {
+			setField(result, "price", comics.Price.String(), price.String())
+			comics.Price = price
+		}
+		if upc != "" {
+			setField(result, "upc", comics.UPC, upc)
+			comics.UPC = upc
+		}
+		setField(result, "item_code", comics.ItemCode, row.ItemCode)
+		comics.ItemCode = row.ItemCode
+
+		if row.Publisher != "" {
+			var current string
+			if comics.PublisherID != nil {
+				current, err = publisherName(im.Models, publishers, *comics.PublisherID)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if !strings.EqualFold(current, row.Publisher) {
+				result.Changes["publisher"] = Change{From: current, To: row.Publisher}
+				result.publisher = row.Publisher
+			}
+		}
+
+		// A UPC that already belongs to a different issue would break the unique index
+		// on the column.
+		if upc != "" {
+			other, err := im.Models.Comics.GetByItemCode("", upc)
+			switch {
+			case err == nil && other.ID != result.ComicsID:
+				v.AddError("upc", fmt.Sprintf("already belongs to issue %d", other.ID))
+			case err != nil && !errors.Is(err, data.ErrRecordNotFound):
+				return nil, err
+			}
+		}
+
+		data.ValidateComics(v, comics)
+
+		switch {
+		case !v.Valid():
+			result.Action = ActionInvalid
+			result.Errors = v.Errors
+			report.Invalid++
+		case existing == nil:
+			result.Action = ActionCreate
+			report.Created++
+		case len(result.Changes) > 0:
+			result.Action = ActionUpdate
+			report.Updated++
+		default:
+			result.Action = ActionUnchanged
+			report.Unchanged++
+		}
+
+		switch {
+		case result.Action == ActionInvalid:
+		case received[row.Line]:
+			result.AlreadyReceived = true
+		default:
+			result.Received = int32(quantity)
+			report.Received += quantity
+		}
+		if len(result.Changes) == 0 {
+			result.Changes = nil
+		}
+
+		result.comics = comics
+		result.quantity = int32(quantity)
+		report.Rows = append(report.Rows, result)
+	}
+
+	return report, nil
+}
+
+// Apply() carries out a plan made by Plan(), which must be valid. The whole list is
+// imported in a single transaction, so a database error leaves nothing imported.
+// Publishers and series named on the list that aren't in the catalogue yet are created.
+func (im *Importer) Apply(report *Report) error {
+	if !report.Valid() {
+		return errors.New("shipping list has invalid rows")
+	}
+
+	shipment := &data.Shipment{
+		ListHash: im.ListHash,
+		Location: im.Location,
+		UserID:   im.UserID,
+		Note:     im.Note,
+	}
+	for _, result := range report.Rows {
+		line := &data.ShipmentLine{
+			Line:      result.Line,
+			Comics:    result.comics,
+			Update:    result.Action == ActionUpdate,
+			Publisher: result.publisher,
+			Quantity:  result.quantity,
+		}
+		if result.Action == ActionCreate && result.comics.IssueNumber != "" {
+			line.Series = result.row.Title
+		}
+		shipment.Lines = append(shipment.Lines, line)
+	}
+
+	err := im.Models.Shipments.Apply(shipment)
+	if err != nil {
+		return err
+	}
+
+	report.Received = 0
+	for i, line := range shipment.Lines {
+		result := report.Rows[i]
+		result.ComicsID = line.Comics.ID
+		result.AlreadyReceived = line.AlreadyReceived
+		if line.AlreadyReceived {
+			result.Received = 0
+		} else {
+			result.Received = line.Quantity
+		}
+		report.Received += int(result.Received)
+	}
+
+	report.DryRun = false
+	return nil
+}
+
+// The setField() helper records a change of a field in the result, unless the value
+// stays the same.
+func setField(result *Result, field, from, to string) {
+	if from != to {
+		result.Changes[field] = Change{From: from, To: to}
+	}
+}
+
+// The publisherName() helper returns the name of a publisher, caching the names it has
+// looked up already since shipping lists mostly come from a handful of publishers.
+func publisherName(models data.Models, cache map[int64]string, id int64) (string, error) {
+	if name, ok := cache[id]; ok {
+		return name, nil
+	}
+	publisher, err := models.Publishers.Get(id)
+	if err != nil {
+		return "", err
+	}
+	cache[id] = publisher.Name
+	return publisher.Name, nil
+}
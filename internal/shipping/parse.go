@@ -0,0 +1,159 @@
+package shipping
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	ErrEmptyList     = errors.New("shipping list is empty")
+	ErrMissingColumn = errors.New("shipping list is missing a column")
+)
+
+// A Row is a single line of a shipping list, with its values exactly as the distributor
+// sent them. Line is the number of the record in the file, counting the header as 1. It
+// matches the line number unless the file has blank lines or multi-line values.
+type Row struct {
+	Line      int    `json:"line"`
+	ItemCode  string `json:"item_code"`
+	Title     string `json:"title"`
+	Issue     string `json:"issue,omitempty"`
+	Price     string `json:"price,omitempty"`
+	UPC       string `json:"upc,omitempty"`
+	Publisher string `json:"publisher,omitempty"`
+	Quantity  string `json:"quantity"`
+}
+
+// columns maps the header names distributors use to the Row field they fill. Header
+// names are compared in lower case, with spaces, dashes, underscores and dots removed and
+// "#" spelled as "no", so "Issue #" and "issue_no" both name the issue column.
+var columns = map[string]string{
+	"itemcode":    "item_code",
+	"item":        "item_code",
+	"code":        "item_code",
+	"diamondcode": "item_code",
+	"stockno":     "item_code",
+	"title":       "title",
+	"description": "title",
+	"issue":       "issue",
+	"issueno":     "issue",
+	"issuenumber": "issue",
+	"number":      "issue",
+	"price":       "price",
+	"retail":      "price",
+	"retailprice": "price",
+	"srp":         "price",
+	"msrp":        "price",
+	"upc":         "upc",
+	"barcode":     "upc",
+	"publisher":   "publisher",
+	"vendor":      "publisher",
+	"qty":         "quantity",
+	"quantity":    "quantity",
+	"qtyshipped":  "quantity",
+	"shipped":     "quantity",
+	"shipqty":     "quantity",
+}
+
+var headerReplacer = strings.NewReplacer(" ", "", "-", "", "_", "", ".", "", "#", "no")
+
+// requiredColumns are the columns every shipping list must have.
+var requiredColumns = []string{"item_code", "title", "quantity"}
+
+// Parse() reads a shipping list. The first line must be a header naming the columns,
+// which may come in any order; unknown columns are ignored. Lists separated by tabs are
+// recognised by a tab in the header line, anything else is read as comma separated.
+func Parse(r io.Reader) ([]*Row, error) {
+	br := bufio.NewReader(r)
+
+	// Skip a UTF-8 byte order mark, which spreadsheet programs like to add.
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+
+	header, err := br.Peek(4096)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if i := bytes.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if bytes.IndexByte(header, '\t') >= 0 {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+
+	names, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrEmptyList
+		}
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	for i, name := range names {
+		key := headerReplacer.Replace(strings.ToLower(strings.TrimSpace(name)))
+		if field, ok := columns[key]; ok {
+			if _, seen := index[field]; !seen {
+				index[field] = i
+			}
+		}
+	}
+	for _, field := range requiredColumns {
+		if _, ok := index[field]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, field)
+		}
+	}
+
+	value := func(record []string, field string) string {
+		i, ok := index[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []*Row
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := &Row{
+			Line:      line,
+			ItemCode:  value(record, "item_code"),
+			Title:     value(record, "title"),
+			Issue:     value(record, "issue"),
+			Price:     value(record, "price"),
+			UPC:       value(record, "upc"),
+			Publisher: value(record, "publisher"),
+			Quantity:  value(record, "quantity"),
+		}
+
+		// Distributors end their lists with totals and blank separator lines; rows with
+		// neither an item code nor a title can't be anything else.
+		if row.ItemCode == "" && row.Title == "" {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrEmptyList
+	}
+	return rows, nil
+}
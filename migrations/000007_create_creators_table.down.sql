@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS comics_creators;
+DROP TABLE IF EXISTS creators;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS creators (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS comics_creators (
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    creator_id bigint NOT NULL REFERENCES creators ON DELETE CASCADE,
+    role text NOT NULL,
+    PRIMARY KEY (comic_id, creator_id, role)
+);
+
+ALTER TABLE comics_creators ADD CONSTRAINT comics_creators_role_check
+    CHECK (role IN ('writer', 'penciller', 'inker', 'colorist', 'letterer', 'cover_artist'));
+
+CREATE INDEX IF NOT EXISTS comics_creators_creator_id_idx ON comics_creators (creator_id);
+CREATE INDEX IF NOT EXISTS creators_name_idx ON creators USING GIN (to_tsvector('simple', name));
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS comics_series_id_issue_sort_idx;
+ALTER TABLE comics DROP COLUMN IF EXISTS issue_sort;
+ALTER TABLE comics DROP COLUMN IF EXISTS issue_number;
+ALTER TABLE comics DROP COLUMN IF EXISTS series_id;
+DROP TABLE IF EXISTS series;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS series (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    publisher text NOT NULL DEFAULT '',
+    volume integer NOT NULL DEFAULT 1,
+    start_year integer NOT NULL,
+    status text NOT NULL DEFAULT 'ongoing',
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE series ADD CONSTRAINT series_volume_check CHECK (volume > 0);
+ALTER TABLE series ADD CONSTRAINT series_status_check
+    CHECK (status IN ('ongoing', 'completed', 'cancelled', 'hiatus'));
+
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS series_id bigint REFERENCES series ON DELETE SET NULL;
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS issue_number text NOT NULL DEFAULT '';
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS issue_sort text COLLATE "C" NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS comics_series_id_issue_sort_idx ON comics (series_id, issue_sort);
+CREATE INDEX IF NOT EXISTS series_name_idx ON series USING GIN (to_tsvector('simple', name));
@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE code = 'publishers:write';
+DROP INDEX IF EXISTS comics_imprint_id_idx;
+DROP INDEX IF EXISTS comics_publisher_id_idx;
+ALTER TABLE comics DROP COLUMN IF EXISTS imprint_id;
+ALTER TABLE comics DROP COLUMN IF EXISTS publisher_id;
+DROP TABLE IF EXISTS imprints;
+DROP TABLE IF EXISTS publishers;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS publishers (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name citext UNIQUE NOT NULL,
+    country text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS imprints (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    publisher_id bigint NOT NULL REFERENCES publishers ON DELETE CASCADE,
+    name citext NOT NULL,
+    version integer NOT NULL DEFAULT 1,
+    UNIQUE (publisher_id, name)
+);
+
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS publisher_id bigint REFERENCES publishers ON DELETE SET NULL;
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS imprint_id bigint REFERENCES imprints ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS comics_publisher_id_idx ON comics (publisher_id);
+CREATE INDEX IF NOT EXISTS comics_imprint_id_idx ON comics (imprint_id);
+
+INSERT INTO permissions (code)
+VALUES ('publishers:write');
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS comic_variants (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    cover_label text NOT NULL,
+    ratio text NOT NULL DEFAULT '',
+    artist text NOT NULL DEFAULT '',
+    sku text UNIQUE NOT NULL,
+    price bigint NOT NULL DEFAULT 0,
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE comic_variants ADD CONSTRAINT comic_variants_price_check CHECK (price >= 0);
+
+CREATE INDEX IF NOT EXISTS comic_variants_comic_id_idx ON comic_variants (comic_id);
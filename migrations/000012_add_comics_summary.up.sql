@@ -0,0 +1 @@
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS summary text NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS comics_owners;
+ALTER TABLE comics DROP COLUMN IF EXISTS archive_key;
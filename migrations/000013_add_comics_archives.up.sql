@@ -0,0 +1,8 @@
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS archive_key text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS comics_owners (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, comic_id)
+);
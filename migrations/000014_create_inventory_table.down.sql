@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE code = 'inventory:write';
+DROP TABLE IF EXISTS stock_adjustments;
+DROP TABLE IF EXISTS inventory;
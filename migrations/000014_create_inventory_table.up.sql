@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS inventory (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    variant_id bigint REFERENCES comic_variants ON DELETE CASCADE,
+    location text NOT NULL,
+    on_hand integer NOT NULL DEFAULT 0,
+    reserved integer NOT NULL DEFAULT 0,
+    reorder_point integer NOT NULL DEFAULT 0,
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE inventory ADD CONSTRAINT inventory_on_hand_check CHECK (on_hand >= 0);
+ALTER TABLE inventory ADD CONSTRAINT inventory_reserved_check CHECK (reserved >= 0 AND reserved <= on_hand);
+ALTER TABLE inventory ADD CONSTRAINT inventory_reorder_point_check CHECK (reorder_point >= 0);
+
+-- A NULL variant_id means the issue's main cover, so treat it as a real value when
+-- checking that each item is only stocked once per location.
+CREATE UNIQUE INDEX IF NOT EXISTS inventory_item_location_idx ON inventory (comic_id, COALESCE(variant_id, 0), location);
+
+CREATE TABLE IF NOT EXISTS stock_adjustments (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    inventory_id bigint NOT NULL REFERENCES inventory ON DELETE CASCADE,
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    delta integer NOT NULL,
+    reason text NOT NULL,
+    note text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS stock_adjustments_inventory_id_idx ON stock_adjustments (inventory_id);
+
+INSERT INTO permissions (code)
+VALUES ('inventory:write');
@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE code = 'orders:manage';
+DROP TABLE IF EXISTS order_allocations;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS cart_items;
+ALTER TABLE comics DROP CONSTRAINT IF EXISTS comics_price_check;
+ALTER TABLE comics DROP COLUMN IF EXISTS price;
@@ -0,0 +1,56 @@
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS price bigint NOT NULL DEFAULT 0;
+ALTER TABLE comics ADD CONSTRAINT comics_price_check CHECK (price >= 0);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    variant_id bigint REFERENCES comic_variants ON DELETE CASCADE,
+    quantity integer NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE cart_items ADD CONSTRAINT cart_items_quantity_check CHECK (quantity BETWEEN 1 AND 100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS cart_items_user_item_idx ON cart_items (user_id, comic_id, COALESCE(variant_id, 0));
+
+CREATE TABLE IF NOT EXISTS orders (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    status text NOT NULL DEFAULT 'pending',
+    total bigint NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('pending', 'paid', 'picking', 'shipped', 'cancelled', 'refunded'));
+
+CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders (user_id);
+
+-- Order items copy the title and price of what was bought, so that orders keep making
+-- sense after the catalogue changes.
+CREATE TABLE IF NOT EXISTS order_items (
+    id bigserial PRIMARY KEY,
+    order_id bigint NOT NULL REFERENCES orders ON DELETE CASCADE,
+    comic_id bigint REFERENCES comics ON DELETE SET NULL,
+    variant_id bigint REFERENCES comic_variants ON DELETE SET NULL,
+    title text NOT NULL,
+    unit_price bigint NOT NULL,
+    quantity integer NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_items_order_id_idx ON order_items (order_id);
+
+-- Allocations record which inventory items the copies of an order item were taken
+-- from, so that they can be put back if the order is cancelled.
+CREATE TABLE IF NOT EXISTS order_allocations (
+    order_item_id bigint NOT NULL REFERENCES order_items ON DELETE CASCADE,
+    inventory_id bigint NOT NULL REFERENCES inventory ON DELETE CASCADE,
+    quantity integer NOT NULL,
+    PRIMARY KEY (order_item_id, inventory_id)
+);
+
+INSERT INTO permissions (code)
+VALUES ('orders:manage');
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    order_id bigint NOT NULL REFERENCES orders ON DELETE CASCADE,
+    amount bigint NOT NULL,
+    currency text NOT NULL DEFAULT 'USD',
+    provider text NOT NULL,
+    reference text NOT NULL DEFAULT '',
+    status text NOT NULL,
+    decline_code text NOT NULL DEFAULT '',
+    card_last4 text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE payments ADD CONSTRAINT payments_amount_check CHECK (amount > 0);
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+    CHECK (status IN ('authorized', 'captured', 'declined', 'refunded', 'failed'));
+
+CREATE INDEX IF NOT EXISTS payments_user_id_idx ON payments (user_id);
+CREATE INDEX IF NOT EXISTS payments_order_id_idx ON payments (order_id);
+CREATE INDEX IF NOT EXISTS payments_reference_idx ON payments (provider, reference);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pull_list_holds;
+DROP TABLE IF EXISTS pull_list;
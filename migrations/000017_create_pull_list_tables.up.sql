@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS pull_list (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    series_name text NOT NULL,
+    series_key text NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS pull_list_user_series_idx ON pull_list (user_id, series_key);
+CREATE INDEX IF NOT EXISTS pull_list_series_key_idx ON pull_list (series_key);
+
+CREATE TABLE IF NOT EXISTS pull_list_holds (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    subscription_id bigint REFERENCES pull_list ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS pull_list_holds_user_comic_idx ON pull_list_holds (user_id, comic_id);
+CREATE INDEX IF NOT EXISTS pull_list_holds_comic_id_idx ON pull_list_holds (comic_id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS comics_street_date_idx;
+
+ALTER TABLE comics DROP CONSTRAINT IF EXISTS comics_year_check;
+ALTER TABLE comics ADD CONSTRAINT comics_year_check CHECK (year BETWEEN 1888 AND date_part('year', now()));
+
+ALTER TABLE comics DROP CONSTRAINT IF EXISTS comics_foc_date_check;
+
+ALTER TABLE comics DROP COLUMN IF EXISTS foc_date;
+ALTER TABLE comics DROP COLUMN IF EXISTS on_sale_date;
+ALTER TABLE comics DROP COLUMN IF EXISTS release_date;
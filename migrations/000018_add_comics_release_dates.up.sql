@@ -0,0 +1,11 @@
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS release_date date;
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS on_sale_date date;
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS foc_date date;
+
+ALTER TABLE comics ADD CONSTRAINT comics_foc_date_check CHECK (foc_date < on_sale_date);
+
+-- Upcoming issues are solicited months ahead, so their year may be next year.
+ALTER TABLE comics DROP CONSTRAINT IF EXISTS comics_year_check;
+ALTER TABLE comics ADD CONSTRAINT comics_year_check CHECK (year BETWEEN 1888 AND date_part('year', now()) + 1);
+
+CREATE INDEX IF NOT EXISTS comics_street_date_idx ON comics (COALESCE(on_sale_date, release_date));
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS comics_upc_idx;
+DROP INDEX IF EXISTS comics_item_code_idx;
+
+ALTER TABLE comics DROP COLUMN IF EXISTS upc;
+ALTER TABLE comics DROP COLUMN IF EXISTS item_code;
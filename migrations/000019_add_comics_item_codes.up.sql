@@ -0,0 +1,6 @@
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS item_code text NOT NULL DEFAULT '';
+ALTER TABLE comics ADD COLUMN IF NOT EXISTS upc text NOT NULL DEFAULT '';
+
+-- Most issues entered by hand have neither, so only enforce uniqueness when set.
+CREATE UNIQUE INDEX IF NOT EXISTS comics_item_code_idx ON comics (item_code) WHERE item_code <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS comics_upc_idx ON comics (upc) WHERE upc <> '';
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS preorders (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    quantity integer NOT NULL,
+    notified_at timestamp(0) with time zone,
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE preorders ADD CONSTRAINT preorders_quantity_check CHECK (quantity BETWEEN 1 AND 100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS preorders_user_comic_idx ON preorders (user_id, comic_id);
+CREATE INDEX IF NOT EXISTS preorders_comic_id_idx ON preorders (comic_id);
+CREATE INDEX IF NOT EXISTS preorders_pending_notification_idx ON preorders (comic_id) WHERE notified_at IS NULL;
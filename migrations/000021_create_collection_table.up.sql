@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS collection_items (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    grade text NOT NULL,
+    grade_value numeric(3, 1) NOT NULL,
+    purchase_price bigint NOT NULL DEFAULT 0,
+    purchase_date date,
+    notes text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE collection_items ADD CONSTRAINT collection_items_grade_value_check CHECK (grade_value BETWEEN 0.5 AND 10.0);
+ALTER TABLE collection_items ADD CONSTRAINT collection_items_purchase_price_check CHECK (purchase_price >= 0);
+
+-- Collectors often own several copies of the same issue, so there's no unique index on
+-- user_id and comic_id.
+CREATE INDEX IF NOT EXISTS collection_items_user_id_idx ON collection_items (user_id);
+CREATE INDEX IF NOT EXISTS collection_items_comic_id_idx ON collection_items (comic_id);
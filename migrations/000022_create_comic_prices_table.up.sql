@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS comic_prices (
+    id bigserial PRIMARY KEY,
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    variant_id bigint REFERENCES comic_variants ON DELETE CASCADE,
+    price bigint NOT NULL,
+    effective_date date NOT NULL DEFAULT CURRENT_DATE,
+    recorded_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE comic_prices ADD CONSTRAINT comic_prices_price_check CHECK (price >= 0);
+
+-- A price is recorded at most once a day; a second change on the same day replaces the
+-- first.
+CREATE UNIQUE INDEX IF NOT EXISTS comic_prices_comic_variant_date_idx
+    ON comic_prices (comic_id, (COALESCE(variant_id, 0)), effective_date);
+
+-- Start the history of existing issues and variants with their current price.
+INSERT INTO comic_prices (comic_id, price, effective_date)
+SELECT id, price, created_at::date FROM comics;
+
+INSERT INTO comic_prices (comic_id, variant_id, price, effective_date)
+SELECT comic_id, id, price, created_at::date FROM comic_variants;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    rating smallint NOT NULL,
+    body text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE reviews ADD CONSTRAINT reviews_rating_check CHECK (rating BETWEEN 1 AND 5);
+
+CREATE UNIQUE INDEX IF NOT EXISTS reviews_comic_user_idx ON reviews (comic_id, user_id);
+CREATE INDEX IF NOT EXISTS reviews_user_id_idx ON reviews (user_id);
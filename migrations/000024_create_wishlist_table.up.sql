@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS wishlist (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    was_available boolean NOT NULL DEFAULT false,
+    notified_at timestamp(0) with time zone
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS wishlist_user_comic_idx ON wishlist (user_id, comic_id);
+CREATE INDEX IF NOT EXISTS wishlist_comic_id_idx ON wishlist (comic_id);
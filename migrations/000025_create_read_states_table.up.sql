@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS read_states (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    status text NOT NULL,
+    last_page integer NOT NULL DEFAULT 0,
+    read_date date,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, comic_id)
+);
+
+-- Unread issues have no read state, so only the other two statuses are stored.
+ALTER TABLE read_states ADD CONSTRAINT read_states_status_check CHECK (status IN ('reading', 'read'));
+ALTER TABLE read_states ADD CONSTRAINT read_states_last_page_check CHECK (last_page >= 0);
+
+CREATE INDEX IF NOT EXISTS read_states_comic_id_idx ON read_states (comic_id);
@@ -0,0 +1,7 @@
+-- An email change waits here until the new address is confirmed, so users.email always
+-- holds an address the user has shown they can receive mail at.
+CREATE TABLE IF NOT EXISTS email_changes (
+    user_id bigint PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+    email citext NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+-- Every login starts a token family: the authentication and refresh tokens issued with it
+-- and all tokens they are rotated into. Used refresh tokens are kept until they expire so
+-- that presenting one again can be spotted, and the whole family revoked.
+CREATE SEQUENCE IF NOT EXISTS token_families_seq;
+
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS family bigint;
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS used_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS tokens_family_idx ON tokens (family);
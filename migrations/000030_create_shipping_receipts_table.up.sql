@@ -0,0 +1,12 @@
+-- Each line of a shipping list that has been received into the inventory. Lists are
+-- identified by the SHA-256 hash of their contents, so uploading the same list again
+-- doesn't receive its copies twice.
+CREATE TABLE IF NOT EXISTS shipping_receipts (
+    list_hash bytea NOT NULL,
+    line integer NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    comic_id bigint NOT NULL REFERENCES comics ON DELETE CASCADE,
+    location text NOT NULL,
+    quantity integer NOT NULL,
+    PRIMARY KEY (list_hash, line)
+);
@@ -0,0 +1 @@
+ALTER TABLE wishlist DROP COLUMN IF EXISTS notify_pending;
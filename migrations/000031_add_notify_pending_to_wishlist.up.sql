@@ -0,0 +1 @@
+ALTER TABLE wishlist ADD COLUMN IF NOT EXISTS notify_pending boolean NOT NULL DEFAULT false;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS payments_order_open_idx;
+
+UPDATE payments SET status = 'failed' WHERE status = 'pending';
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+    CHECK (status IN ('authorized', 'captured', 'declined', 'refunded', 'failed'));
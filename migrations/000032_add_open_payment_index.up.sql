@@ -0,0 +1,6 @@
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+    CHECK (status IN ('pending', 'authorized', 'captured', 'declined', 'refunded', 'failed'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS payments_order_open_idx ON payments (order_id)
+    WHERE status IN ('pending', 'authorized', 'captured');